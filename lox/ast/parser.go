@@ -2,7 +2,12 @@ package ast
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
 	"golox/lox/expression"
+	"golox/lox/pattern"
 	"golox/lox/reporter"
 	"golox/lox/statement"
 	"golox/lox/token"
@@ -12,6 +17,10 @@ type Parser struct {
 	tokens   []*token.Token
 	current  int
 	reporter *reporter.ErrorReporter
+	// groupDepth counts the open parentheses, inside of which new lines are ignored
+	groupDepth int
+	// yields is set once a yield statement is parsed in the current function body
+	yields bool
 }
 
 func NewParser(tokens []*token.Token, reporter *reporter.ErrorReporter) *Parser {
@@ -23,16 +32,18 @@ func NewParser(tokens []*token.Token, reporter *reporter.ErrorReporter) *Parser
 }
 
 func (p *Parser) Parse() ([]statement.Stmt, error) {
+	var parseErr error
 	statements := make([]statement.Stmt, 0)
-	for !p.isAtEnd() {
+	for p.skipNewlines(); !p.isAtEnd(); p.skipNewlines() {
 		stmt, err := p.declaration()
 		if err != nil {
+			parseErr = err
 			p.synchronize()
 			continue
 		}
 		statements = append(statements, stmt)
 	}
-	return statements, nil
+	return statements, parseErr
 }
 
 func (p *Parser) declaration() (statement.Stmt, error) {
@@ -46,6 +57,12 @@ func (p *Parser) declaration() (statement.Stmt, error) {
 		return p.varDeclaration()
 
 	}
+	if p.match(token.Const) {
+		return p.constDeclaration()
+	}
+	if p.match(token.Import) {
+		return p.importDeclaration()
+	}
 	return p.statement()
 }
 
@@ -55,45 +72,116 @@ func (p *Parser) classDeclaration() (*statement.ClassStmt, error) {
 		return nil, err
 	}
 
-	methods := make([]*statement.FunctionStmt, 10)
-	for !p.check(token.End) && !p.isAtEnd() {
-		fn, err := p.function("method")
-		if err != nil {
+	var superclass *expression.Variable
+	if p.match(token.Less) {
+		if _, err = p.consume(token.Identifier, "expect superclass name"); err != nil {
 			return nil, err
 		}
-		methods = append(methods, fn)
+		superclass = expression.NewVariable(p.previous())
+	}
+
+	var (
+		fields                          []*statement.VarStmt
+		methods, staticMethods, getters []*statement.FunctionStmt
+	)
+	for p.skipNewlines(); !p.check(token.End) && !p.isAtEnd(); p.skipNewlines() {
+		switch {
+		case p.match(token.Var):
+			field, err := p.varDeclaration()
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field.(*statement.VarStmt))
+		case p.checkModifier("static"):
+			p.advance()
+			fn, err := p.function("static method")
+			if err != nil {
+				return nil, err
+			}
+			staticMethods = append(staticMethods, fn)
+		case p.checkModifier("get"):
+			p.advance()
+			fn, err := p.function("getter")
+			if err != nil {
+				return nil, err
+			}
+			if len(fn.Params) > 0 || fn.Rest != nil {
+				return nil, p.reporter.Report(fmt.Sprintf("getter '%s' can't have parameters", fn.Name.Lexeme), fn.Name)
+			}
+			getters = append(getters, fn)
+		default:
+			fn, err := p.function("method")
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, fn)
+		}
 	}
 
 	if _, err = p.consume(token.End, "expect 'end' after class definition"); err != nil {
 		return nil, err
 	}
 
-	return statement.NewClassStmt(name, methods), nil
+	return statement.NewClassStmt(name, superclass, fields, methods, staticMethods, getters), nil
+}
+
+// checkModifier checks for a word like 'static' in front of a method name. The
+// words aren't keywords, so methods can still be called 'static' or 'get'.
+func (p *Parser) checkModifier(word string) bool {
+	if !p.check(token.Identifier) || p.peek().Lexeme != word || p.current+1 >= len(p.tokens) {
+		return false
+	}
+	return p.tokens[p.current+1].Type == token.Identifier
 }
 
-// TODO: Add support for anonymous functions:
-// var a = func() <do something> end
-// call_func(func() <do something>; end, second_param);
 func (p *Parser) function(kind string) (*statement.FunctionStmt, error) {
 	name, err := p.consume(token.Identifier, "expect "+kind+" name")
 	if err != nil {
 		return nil, err
 	}
+	return p.functionBody(name, kind)
+}
+
+// functionBody parses the parameter list and the body of a function. Name is nil for
+// anonymous functions.
+func (p *Parser) functionBody(name *token.Token, kind string) (*statement.FunctionStmt, error) {
+	var err error
 	if _, err = p.consume(token.LeftParen, "expect '(' after "+kind+" name"); err != nil {
 		return nil, err
 	}
+	p.groupDepth++
 	params := make([]*token.Token, 0)
+	defaults := make([]expression.Expression, 0)
+	var rest *token.Token
 	if !p.check(token.RightParen) {
 		for {
 			if len(params) >= 255 {
 				err = p.reporter.Report("can't have more than 255 function parameters", p.peek())
 				return nil, err
 			}
+			if p.match(token.Ellipsis) {
+				if rest, err = p.consume(token.Identifier, "expect parameter name after '...'"); err != nil {
+					return nil, err
+				}
+				if !p.check(token.RightParen) {
+					return nil, p.reporter.Report("the '...' parameter must be the last one", rest)
+				}
+				break
+			}
 			param, err := p.consume(token.Identifier, "expect parameter name")
 			if err != nil {
 				return nil, err
 			}
+			var defaultValue expression.Expression
+			if p.match(token.Equal) {
+				if defaultValue, err = p.expression(); err != nil {
+					return nil, err
+				}
+			} else if len(defaults) > 0 && defaults[len(defaults)-1] != nil {
+				return nil, p.reporter.Report(fmt.Sprintf("parameter '%s' without a default value can't follow one with a default", param.Lexeme), param)
+			}
 			params = append(params, param)
+			defaults = append(defaults, defaultValue)
 			if !p.match(token.Comma) {
 				break
 			}
@@ -102,32 +190,103 @@ func (p *Parser) function(kind string) (*statement.FunctionStmt, error) {
 	if _, err = p.consume(token.RightParen, "expect ')' after "+kind+" parameters"); err != nil {
 		return nil, err
 	}
+	p.groupDepth--
+
+	// new lines end statements in the body, even for an anonymous function passed
+	// as an argument
+	groupDepth, yields := p.groupDepth, p.yields
+	p.groupDepth, p.yields = 0, false
 	body, err := p.block(token.End)
+	isGenerator := p.yields
+	p.groupDepth, p.yields = groupDepth, yields
 	if err != nil {
 		return nil, err
 	}
-	return statement.NewFunctionStmt(name, params, body), nil
+	return statement.NewFunctionStmt(name, params, defaults, rest, body, isGenerator), nil
 }
 
 func (p *Parser) varDeclaration() (statement.Stmt, error) {
-	name, err := p.consume(token.Identifier, "expect variable name")
+	names := make([]*token.Token, 0)
+	for {
+		name, err := p.consume(token.Identifier, "expect variable name")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if !p.match(token.Comma) {
+			break
+		}
+	}
+
+	var (
+		initializers []expression.Expression
+		err          error
+	)
+	if p.match(token.Equal) {
+		if initializers, err = p.expressionList(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = p.consumeTerminator("expect ';' or new line after variable declaration"); err != nil {
+		return nil, err
+	}
+
+	return statement.NewVarStmt(names, initializers), nil
+}
+
+func (p *Parser) constDeclaration() (statement.Stmt, error) {
+	name, err := p.consume(token.Identifier, "expect constant name")
 	if err != nil {
 		return nil, err
 	}
+	if _, err = p.consume(token.Equal, "expect '=' after constant name, constants need a value"); err != nil {
+		return nil, err
+	}
+	initializer, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.consumeTerminator("expect ';' or new line after constant declaration"); err != nil {
+		return nil, err
+	}
+	return statement.NewConstStmt(name, initializer), nil
+}
 
-	var initializer expression.Expression = nil
-	if p.match(token.Equal) {
-		initializer, err = p.expression()
-		if err != nil {
+func (p *Parser) importDeclaration() (statement.Stmt, error) {
+	keyword := p.previous()
+	path, err := p.consume(token.String, "expect module path after 'import'")
+	if err != nil {
+		return nil, err
+	}
+
+	var name *token.Token
+	if p.match(token.As) {
+		if name, err = p.consume(token.Identifier, "expect module name after 'as'"); err != nil {
 			return nil, err
 		}
+	} else {
+		base := strings.TrimSuffix(filepath.Base(path.Literal.(string)), filepath.Ext(path.Literal.(string)))
+		if !isIdentifier(base) {
+			return nil, p.reporter.Report(fmt.Sprintf("module file name '%s' isn't a valid name, import it with 'as'", base), path)
+		}
+		name = token.NewToken(token.Identifier, base, nil, path.Line, path.Source)
+		name.File = path.File
 	}
 
-	if _, err = p.consume(token.Semicolon, "expect ';' after variable declaration"); err != nil {
+	if err = p.consumeTerminator("expect ';' or new line after import"); err != nil {
 		return nil, err
 	}
+	return statement.NewImportStmt(keyword, path.Literal.(string), name), nil
+}
 
-	return statement.NewVarStmt(name, initializer), nil
+func isIdentifier(name string) bool {
+	for i, c := range name {
+		if !(c == '_' || unicode.IsLetter(c) || (i > 0 && unicode.IsDigit(c))) {
+			return false
+		}
+	}
+	return name != ""
 }
 
 func (p *Parser) statement() (statement.Stmt, error) {
@@ -137,6 +296,9 @@ func (p *Parser) statement() (statement.Stmt, error) {
 	if p.match(token.If) {
 		return p.ifStmt()
 	}
+	if p.match(token.Match) {
+		return p.matchStmt()
+	}
 	if p.match(token.Print) {
 		return p.printStmt()
 	}
@@ -146,6 +308,32 @@ func (p *Parser) statement() (statement.Stmt, error) {
 	if p.match(token.While) {
 		return p.whileStmt()
 	}
+	if p.match(token.Repeat) {
+		return p.repeatStmt()
+	}
+	if p.match(token.Try) {
+		return p.tryStmt()
+	}
+	if p.match(token.Throw) {
+		return p.throwStmt()
+	}
+	if p.match(token.Yield) {
+		return p.yieldStmt()
+	}
+	if p.match(token.Break) {
+		keyword := p.previous()
+		if err := p.consumeTerminator("expect ';' or new line after 'break'"); err != nil {
+			return nil, err
+		}
+		return statement.NewBreakStmt(keyword), nil
+	}
+	if p.match(token.Continue) {
+		keyword := p.previous()
+		if err := p.consumeTerminator("expect ';' or new line after 'continue'"); err != nil {
+			return nil, err
+		}
+		return statement.NewContinueStmt(keyword), nil
+	}
 	// loops
 	if p.match(token.Do) {
 		statements, err := p.block(token.End)
@@ -154,8 +342,8 @@ func (p *Parser) statement() (statement.Stmt, error) {
 		}
 		return statement.NewBlockStmt(statements), nil
 	}
-	// blocks
-	if p.match(token.LeftBrace) {
+	// blocks, unless the brace starts a map literal
+	if !p.checkMapLiteral() && p.match(token.LeftBrace) {
 		statements, err := p.block(token.RightBrace)
 		if err != nil {
 			return nil, err
@@ -168,7 +356,7 @@ func (p *Parser) statement() (statement.Stmt, error) {
 func (p *Parser) block(limits ...token.TokenType) ([]statement.Stmt, error) {
 	statements := make([]statement.Stmt, 0)
 
-	for !p.check(limits...) && !p.isAtEnd() {
+	for p.skipNewlines(); !p.check(limits...) && !p.isAtEnd(); p.skipNewlines() {
 		decl, err := p.declaration()
 		if err != nil {
 			return nil, err
@@ -189,6 +377,7 @@ func (p *Parser) ifStmt() (statement.Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
+	p.skipNewlines()
 	if _, err := p.consume(token.Then, "expect 'then' after branch condition"); err != nil {
 		return nil, err
 	}
@@ -200,20 +389,24 @@ func (p *Parser) ifStmt() (statement.Stmt, error) {
 	thenBranch := statement.NewBlockStmt(thenStatements)
 
 	var (
-		elifBranches []statement.Stmt
+		elifBranches []*statement.IfStmt
 		elseBranch   statement.Stmt
 	)
 
-	// TODO
 	for p.previous().Type == token.Elif {
-		panic("TODO")
-		/*
-			elifBlock, err := p.block(token.Elif, token.Else, token.End)
-			if err != nil {
-				return nil, err
-			}
-			elifBranches = append(elifBranches, statement.NewBlockStmt(elifBlock))
-		*/
+		elifCondition, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		p.skipNewlines()
+		if _, err := p.consume(token.Then, "expect 'then' after elif condition"); err != nil {
+			return nil, err
+		}
+		elifStatements, err := p.block(token.Elif, token.Else, token.End)
+		if err != nil {
+			return nil, err
+		}
+		elifBranches = append(elifBranches, statement.NewIfStmt(elifCondition, statement.NewBlockStmt(elifStatements), nil, nil))
 	}
 
 	if p.previous().Type == token.Else {
@@ -231,12 +424,119 @@ func (p *Parser) ifStmt() (statement.Stmt, error) {
 	return statement.NewIfStmt(condition, thenBranch, elifBranches, elseBranch), nil
 }
 
+func (p *Parser) matchStmt() (statement.Stmt, error) {
+	keyword := p.previous()
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	p.skipNewlines()
+	if _, err := p.consume(token.Case, "expect 'case' after match value"); err != nil {
+		return nil, err
+	}
+
+	cases := make([]*statement.MatchCase, 0)
+	for p.previous().Type == token.Case {
+		pat, err := p.pattern()
+		if err != nil {
+			return nil, err
+		}
+		var guard expression.Expression
+		if p.match(token.If) {
+			if guard, err = p.expression(); err != nil {
+				return nil, err
+			}
+		}
+		p.skipNewlines()
+		if _, err := p.consume(token.Then, "expect 'then' after case pattern"); err != nil {
+			return nil, err
+		}
+		body, err := p.block(token.Case, token.End)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, statement.NewMatchCase(pat, guard, body))
+	}
+
+	return statement.NewMatchStmt(keyword, value, cases), nil
+}
+
+// pattern parses a literal, '_', a name to bind or a class pattern like
+// 'Point(x: 0, y)'.
+func (p *Parser) pattern() (pattern.Pattern, error) {
+	if p.match(token.False) {
+		return pattern.NewLiteral(p.previous(), false), nil
+	}
+	if p.match(token.True) {
+		return pattern.NewLiteral(p.previous(), true), nil
+	}
+	if p.match(token.Null) {
+		return pattern.NewLiteral(p.previous(), nil), nil
+	}
+	if p.match(token.Number, token.String) {
+		return pattern.NewLiteral(p.previous(), p.previous().Literal), nil
+	}
+	if p.match(token.Minus) {
+		number, err := p.consume(token.Number, "expect number after '-' in pattern")
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := number.Literal.(int64); ok {
+			return pattern.NewLiteral(number, -i), nil
+		}
+		return pattern.NewLiteral(number, -number.Literal.(float64)), nil
+	}
+	if p.match(token.Identifier) {
+		name := p.previous()
+		if name.Lexeme == "_" {
+			return pattern.NewWildcard(name), nil
+		}
+		if p.match(token.LeftParen) {
+			return p.instancePattern(name)
+		}
+		return pattern.NewBinding(name), nil
+	}
+	return nil, p.reporter.Report(fmt.Sprintf("expect pattern, got '%s'", p.peek().Lexeme), p.peek())
+}
+
+func (p *Parser) instancePattern(class *token.Token) (pattern.Pattern, error) {
+	paren := p.previous()
+	p.groupDepth++
+	defer func() { p.groupDepth-- }()
+
+	fields := make([]*token.Token, 0)
+	patterns := make([]pattern.Pattern, 0)
+	if !p.check(token.RightParen) {
+		for {
+			field, err := p.consume(token.Identifier, "expect field name in class pattern")
+			if err != nil {
+				return nil, err
+			}
+			var pat pattern.Pattern = pattern.NewBinding(field)
+			if p.match(token.Colon) {
+				if pat, err = p.pattern(); err != nil {
+					return nil, err
+				}
+			}
+			fields = append(fields, field)
+			patterns = append(patterns, pat)
+			if !p.match(token.Comma) {
+				break
+			}
+		}
+	}
+	if _, err := p.consume(token.RightParen, "expect ')' after class pattern fields"); err != nil {
+		return nil, err
+	}
+	return pattern.NewInstance(expression.NewVariable(class), paren, fields, patterns), nil
+}
+
 func (p *Parser) printStmt() (statement.Stmt, error) {
 	val, err := p.expression()
 	if err != nil {
 		return nil, err
 	}
-	if _, err = p.consume(token.Semicolon, "expect ';' after a value"); err != nil {
+	if err = p.consumeTerminator("expect ';' or new line after a value"); err != nil {
 		return nil, err
 	}
 	return statement.NewPrintStmt(val), nil
@@ -244,19 +544,78 @@ func (p *Parser) printStmt() (statement.Stmt, error) {
 
 func (p *Parser) returnStmt() (statement.Stmt, error) {
 	var (
-		value expression.Expression = expression.MakeNull()
-		err   error                 = nil
+		values []expression.Expression
+		err    error
 	)
 	keyword := p.previous()
-	if !p.check(token.Semicolon) {
-		if value, err = p.expression(); err != nil {
+	if !p.checkTerminator() {
+		if values, err = p.expressionList(); err != nil {
 			return nil, err
 		}
 	}
-	if _, err = p.consume(token.Semicolon, "expect ';' after return"); err != nil {
+	if err = p.consumeTerminator("expect ';' or new line after return"); err != nil {
+		return nil, err
+	}
+	return statement.NewReturnStmt(keyword, values), nil
+}
+
+func (p *Parser) tryStmt() (statement.Stmt, error) {
+	keyword := p.previous()
+	body, err := p.block(token.Catch, token.Finally, token.End)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		catchName              *token.Token
+		catchBody, finallyBody []statement.Stmt
+	)
+	if p.previous().Type == token.Catch {
+		if p.match(token.Identifier) {
+			catchName = p.previous()
+		}
+		if catchBody, err = p.block(token.Finally, token.End); err != nil {
+			return nil, err
+		}
+	}
+	if p.previous().Type == token.Finally {
+		if finallyBody, err = p.block(token.End); err != nil {
+			return nil, err
+		}
+	}
+	if p.previous().Type != token.End {
+		return nil, p.reporter.Report("expected 'end', 'catch' or 'finally' after try statement body", p.previous())
+	}
+	if catchBody == nil && finallyBody == nil {
+		return nil, p.reporter.Report("expect 'catch' or 'finally' after try statement body", keyword)
+	}
+
+	return statement.NewTryStmt(body, catchName, catchBody, finallyBody), nil
+}
+
+func (p *Parser) throwStmt() (statement.Stmt, error) {
+	keyword := p.previous()
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.consumeTerminator("expect ';' or new line after thrown value"); err != nil {
+		return nil, err
+	}
+	return statement.NewThrowStmt(keyword, value), nil
+}
+
+func (p *Parser) yieldStmt() (statement.Stmt, error) {
+	keyword := p.previous()
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.consumeTerminator("expect ';' or new line after yielded value"); err != nil {
 		return nil, err
 	}
-	return statement.NewReturnStmt(keyword, value), nil
+	p.yields = true
+	return statement.NewYieldStmt(keyword, value), nil
 }
 
 func (p *Parser) whileStmt() (statement.Stmt, error) {
@@ -264,6 +623,7 @@ func (p *Parser) whileStmt() (statement.Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
+	p.skipNewlines()
 	if !p.check(token.Do) {
 		return nil, p.reporter.Report("expect 'do' after while conditition", p.peek())
 	}
@@ -273,10 +633,32 @@ func (p *Parser) whileStmt() (statement.Stmt, error) {
 		return nil, err
 	}
 
-	return statement.NewWhileStmt(condition, body), nil
+	return statement.NewWhileStmt(condition, body, nil), nil
+}
+
+func (p *Parser) repeatStmt() (statement.Stmt, error) {
+	body, err := p.block(token.Until)
+	if err != nil {
+		return nil, err
+	}
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.consumeTerminator("expect ';' or new line after 'until' condition"); err != nil {
+		return nil, err
+	}
+	return statement.NewRepeatStmt(body, condition), nil
 }
 
 func (p *Parser) forStmt() (statement.Stmt, error) {
+	if p.checkForIn() {
+		return p.forInStmt()
+	}
+	if p.checkNumericFor() {
+		return p.numericForStmt()
+	}
+
 	var (
 		initializer          statement.Stmt
 		condition, increment expression.Expression
@@ -307,10 +689,12 @@ func (p *Parser) forStmt() (statement.Stmt, error) {
 	}
 
 	// increment
+	p.skipNewlines()
 	if !p.check(token.Do) {
 		if increment, err = p.expression(); err != nil {
 			return nil, err
 		}
+		p.skipNewlines()
 		if !p.check(token.Do) {
 			return nil, p.reporter.Report("expect 'do' after for loop increment", p.peek())
 		}
@@ -322,13 +706,10 @@ func (p *Parser) forStmt() (statement.Stmt, error) {
 	}
 
 	// desugarring in a while loop
-	if increment != nil {
-		body = statement.NewBlockStmt([]statement.Stmt{body, statement.NewExpressionStmt(increment)})
-	}
 	if condition == nil {
 		condition = expression.NewLiteral(true)
 	}
-	body = statement.NewWhileStmt(condition, body)
+	body = statement.NewWhileStmt(condition, body, increment)
 	if initializer != nil {
 		body = statement.NewBlockStmt([]statement.Stmt{initializer, body})
 	}
@@ -336,12 +717,129 @@ func (p *Parser) forStmt() (statement.Stmt, error) {
 	return body, nil
 }
 
+// checkForIn looks ahead for 'name, name in' to tell a for-in loop apart from a
+// C style for loop.
+func (p *Parser) checkForIn() bool {
+	for i := p.current; i+1 < len(p.tokens); i += 2 {
+		if p.tokens[i].Type != token.Identifier {
+			return false
+		}
+		switch p.tokens[i+1].Type {
+		case token.In:
+			return true
+		case token.Comma:
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// checkNumericFor tells 'for i = 1, 10 do' apart from a C style for loop starting
+// with an assignment, by looking for a comma before the end of the assignment.
+func (p *Parser) checkNumericFor() bool {
+	if !p.check(token.Identifier) || p.current+1 >= len(p.tokens) || p.tokens[p.current+1].Type != token.Equal {
+		return false
+	}
+	depth := 0
+	for _, tok := range p.tokens[p.current+2:] {
+		switch tok.Type {
+		case token.LeftParen, token.LeftBracket, token.LeftBrace:
+			depth++
+		case token.RightParen, token.RightBracket, token.RightBrace:
+			depth--
+		case token.Comma:
+			if depth == 0 {
+				return true
+			}
+		case token.Semicolon, token.Do, token.Newline, token.EOF:
+			if depth == 0 {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+func (p *Parser) numericForStmt() (statement.Stmt, error) {
+	name := p.advance()
+	p.advance() // '='
+
+	start, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.consume(token.Comma, "expect ',' after loop start"); err != nil {
+		return nil, err
+	}
+	stop, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	var step expression.Expression
+	if p.match(token.Comma) {
+		if step, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	p.skipNewlines()
+	if !p.check(token.Do) {
+		return nil, p.reporter.Report("expect 'do' after for loop bounds", p.peek())
+	}
+
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	return statement.NewNumericForStmt(name, start, stop, step, body), nil
+}
+
+func (p *Parser) forInStmt() (statement.Stmt, error) {
+	names := []*token.Token{p.advance()}
+	for p.match(token.Comma) {
+		name, err := p.consume(token.Identifier, "expect loop variable name after ','")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	in, err := p.consume(token.In, "expect 'in' after loop variables")
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 2 {
+		return nil, p.reporter.Report("can't have more than 2 loop variables", in)
+	}
+
+	iterable, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	p.skipNewlines()
+	if !p.check(token.Do) {
+		return nil, p.reporter.Report("expect 'do' after for loop iterable", p.peek())
+	}
+
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	return statement.NewForInStmt(names, in, iterable, body), nil
+}
+
 func (p *Parser) expressionStmt() (statement.Stmt, error) {
 	val, err := p.expression()
 	if err != nil {
 		return nil, err
 	}
-	if _, err = p.consume(token.Semicolon, "expect ';' after a value"); err != nil {
+	if p.check(token.Comma) {
+		if val, err = p.multiAssignment(val); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.consumeTerminator("expect ';' or new line after a value"); err != nil {
 		return nil, err
 	}
 	return statement.NewExpressionStmt(val), nil
@@ -351,6 +849,50 @@ func (p *Parser) expression() (expression.Expression, error) {
 	return p.assignment()
 }
 
+// multiAssignment parses the rest of 'a, b.c = x, y' after the first target.
+func (p *Parser) multiAssignment(first expression.Expression) (expression.Expression, error) {
+	targets := []expression.Expression{first}
+	for p.match(token.Comma) {
+		target, err := p.call()
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	equals, err := p.consume(token.Equal, "expect '=' after assignment targets")
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range targets {
+		switch target.(type) {
+		case *expression.Variable, *expression.Get, *expression.Index:
+		default:
+			return nil, p.reporter.Report("invalid assignment target", equals)
+		}
+	}
+	values, err := p.expressionList()
+	if err != nil {
+		return nil, err
+	}
+	return expression.NewMultiAssign(targets, equals, values), nil
+}
+
+// expressionList parses one or more comma separated expressions.
+func (p *Parser) expressionList() ([]expression.Expression, error) {
+	exprs := make([]expression.Expression, 0)
+	for {
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		if !p.match(token.Comma) {
+			break
+		}
+	}
+	return exprs, nil
+}
+
 func (p *Parser) assignment() (expression.Expression, error) {
 	expr, err := p.or()
 	if err != nil {
@@ -368,13 +910,40 @@ func (p *Parser) assignment() (expression.Expression, error) {
 			return expression.NewAssign(name, value), nil
 		} else if v, ok := expr.(*expression.Get); ok {
 			return expression.NewSet(v.Object, v.Name, value), nil
+		} else if v, ok := expr.(*expression.Index); ok {
+			return expression.NewIndexSet(v.Object, v.Bracket, v.Index, value), nil
 		}
 
 		p.reporter.Report("invalid assignment target", equals)
+	} else if p.match(token.PlusEqual, token.MinusEqual, token.StarEqual, token.SlashEqual, token.PercentEqual, token.DotDotEqual) {
+		operator := *p.previous()
+		operator.Type = compoundOperators[operator.Type]
+		value, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+
+		switch expr.(type) {
+		case *expression.Variable, *expression.Get, *expression.Index:
+			return expression.NewCompoundAssign(expr, &operator, value), nil
+		}
+
+		p.reporter.Report("invalid assignment target", &operator)
 	}
 	return expr, nil
 }
 
+// compoundOperators maps compound assignment operators to the binary operators
+// they apply.
+var compoundOperators = map[token.TokenType]token.TokenType{
+	token.PlusEqual:    token.Plus,
+	token.MinusEqual:   token.Minus,
+	token.StarEqual:    token.Star,
+	token.SlashEqual:   token.Slash,
+	token.PercentEqual: token.Percent,
+	token.DotDotEqual:  token.DotDot,
+}
+
 func (p *Parser) or() (expression.Expression, error) {
 	expr, err := p.and()
 	if err != nil {
@@ -430,12 +999,84 @@ func (p *Parser) equality() (expression.Expression, error) {
 }
 
 func (p *Parser) comparison() (expression.Expression, error) {
-	expr, err := p.term()
+	expr, err := p.bitwiseOr()
 	if err != nil {
 		return nil, err
 	}
 
 	for p.match(token.Greater, token.GreaterEqual, token.Less, token.LessEqual) {
+		operator := p.previous()
+		right, err := p.bitwiseOr()
+		if err != nil {
+			return nil, err
+		}
+		expr = expression.NewBinary(expr, operator, right)
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) bitwiseOr() (expression.Expression, error) {
+	expr, err := p.bitwiseXor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(token.Pipe) {
+		operator := p.previous()
+		right, err := p.bitwiseXor()
+		if err != nil {
+			return nil, err
+		}
+		expr = expression.NewBinary(expr, operator, right)
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) bitwiseXor() (expression.Expression, error) {
+	expr, err := p.bitwiseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(token.Caret) {
+		operator := p.previous()
+		right, err := p.bitwiseAnd()
+		if err != nil {
+			return nil, err
+		}
+		expr = expression.NewBinary(expr, operator, right)
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) bitwiseAnd() (expression.Expression, error) {
+	expr, err := p.shift()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(token.Ampersand) {
+		operator := p.previous()
+		right, err := p.shift()
+		if err != nil {
+			return nil, err
+		}
+		expr = expression.NewBinary(expr, operator, right)
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) shift() (expression.Expression, error) {
+	expr, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(token.LessLess, token.GreaterGreater) {
 		operator := p.previous()
 		right, err := p.term()
 		if err != nil {
@@ -471,7 +1112,7 @@ func (p *Parser) factor() (expression.Expression, error) {
 		return nil, err
 	}
 
-	for p.match(token.Slash, token.Star) {
+	for p.match(token.Slash, token.Star, token.Div, token.Percent) {
 		operator := p.previous()
 		right, err := p.concatination()
 		if err != nil {
@@ -510,8 +1151,54 @@ func (p *Parser) unary() (expression.Expression, error) {
 		}
 		return expression.NewUnary(operator, right), nil
 	}
+	if p.match(token.Spawn) {
+		return p.spawn()
+	}
+	if p.match(token.Wait) {
+		keyword := p.previous()
+		task, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return expression.NewWait(keyword, task), nil
+	}
 
-	return p.call()
+	return p.power()
+}
+
+// spawn parses the function call after 'spawn', whose arguments are evaluated
+// before the task starts.
+func (p *Parser) spawn() (expression.Expression, error) {
+	keyword := p.previous()
+	expr, err := p.call()
+	if err != nil {
+		return nil, err
+	}
+	call, ok := expr.(*expression.Call)
+	if !ok {
+		return nil, p.reporter.Report("expect a function call after 'spawn'", keyword)
+	}
+	return expression.NewSpawn(keyword, call), nil
+}
+
+// power parses the right associative '**' operator, which binds tighter than a
+// unary operator on its left: -2 ** 2 is -(2 ** 2).
+func (p *Parser) power() (expression.Expression, error) {
+	expr, err := p.call()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(token.StarStar) {
+		operator := p.previous()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		expr = expression.NewBinary(expr, operator, right)
+	}
+
+	return expr, nil
 }
 
 func (p *Parser) call() (expression.Expression, error) {
@@ -531,6 +1218,10 @@ func (p *Parser) call() (expression.Expression, error) {
 				return nil, err
 			}
 			expr = expression.NewGet(expr, name)
+		} else if p.match(token.LeftBracket) {
+			if expr, err = p.finishIndex(expr); err != nil {
+				return nil, err
+			}
 		} else {
 			break
 		}
@@ -539,17 +1230,35 @@ func (p *Parser) call() (expression.Expression, error) {
 }
 
 func (p *Parser) finishCall(callee expression.Expression) (expression.Expression, error) {
+	p.groupDepth++
+	defer func() { p.groupDepth-- }()
+
 	arguments := make([]expression.Expression, 0)
+	names := make([]*token.Token, 0)
 	if !p.check(token.RightParen) {
 		for {
 			if len(arguments) >= 255 {
 				return nil, p.reporter.Report("maximum number of function arguments (255) exceeded", p.previous())
 			}
+			// named arguments look like 'name: value'
+			var name *token.Token
+			if p.check(token.Identifier) && p.current+1 < len(p.tokens) && p.tokens[p.current+1].Type == token.Colon {
+				name = p.advance()
+				p.advance()
+				for _, other := range names {
+					if other != nil && other.Lexeme == name.Lexeme {
+						return nil, p.reporter.Report(fmt.Sprintf("argument '%s' is given more than once", name.Lexeme), name)
+					}
+				}
+			} else if len(names) > 0 && names[len(names)-1] != nil {
+				return nil, p.reporter.Report("positional argument can't follow a named argument", p.peek())
+			}
 			expr, err := p.expression()
 			if err != nil {
 				return nil, err
 			}
 			arguments = append(arguments, expr)
+			names = append(names, name)
 			if !p.match(token.Comma) {
 				break
 			}
@@ -560,7 +1269,110 @@ func (p *Parser) finishCall(callee expression.Expression) (expression.Expression
 	if err != nil {
 		return nil, err
 	}
-	return expression.NewCall(callee, paren, arguments), nil
+	return expression.NewCall(callee, paren, arguments, names), nil
+}
+
+func (p *Parser) finishIndex(object expression.Expression) (expression.Expression, error) {
+	p.groupDepth++
+	defer func() { p.groupDepth-- }()
+
+	bracket := p.previous()
+	index, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.consume(token.RightBracket, "expect ']' after index"); err != nil {
+		return nil, err
+	}
+	return expression.NewIndex(object, bracket, index), nil
+}
+
+func (p *Parser) list() (expression.Expression, error) {
+	p.groupDepth++
+	defer func() { p.groupDepth-- }()
+
+	bracket := p.previous()
+	elements := make([]expression.Expression, 0)
+	for !p.check(token.RightBracket) {
+		element, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+		if !p.match(token.Comma) {
+			break
+		}
+	}
+	if _, err := p.consume(token.RightBracket, "expect ']' after list elements"); err != nil {
+		return nil, err
+	}
+	return expression.NewList(bracket, elements), nil
+}
+
+// checkMapLiteral reports whether the next tokens start a map literal and not a
+// block, that is a '{' followed by a single token key and a ':'.
+func (p *Parser) checkMapLiteral() bool {
+	if !p.check(token.LeftBrace) || p.current+2 >= len(p.tokens) {
+		return false
+	}
+	return p.tokens[p.current+2].Type == token.Colon
+}
+
+func (p *Parser) mapLiteral() (expression.Expression, error) {
+	p.groupDepth++
+	defer func() { p.groupDepth-- }()
+
+	brace := p.previous()
+	keys := make([]expression.Expression, 0)
+	values := make([]expression.Expression, 0)
+	for !p.check(token.RightBrace) {
+		key, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err = p.consume(token.Colon, "expect ':' after map key"); err != nil {
+			return nil, err
+		}
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+		if !p.match(token.Comma) {
+			break
+		}
+	}
+	if _, err := p.consume(token.RightBrace, "expect '}' after map entries"); err != nil {
+		return nil, err
+	}
+	return expression.NewMap(brace, keys, values), nil
+}
+
+// interpolation parses the expressions embedded in a string, each with a parser of
+// its own over the tokens the scanner produced for it.
+func (p *Parser) interpolation() (expression.Expression, error) {
+	tok := p.previous()
+	parts := make([]expression.Expression, 0)
+	for _, part := range tok.Literal.([]interface{}) {
+		switch v := part.(type) {
+		case string:
+			parts = append(parts, expression.NewLiteral(v))
+		case []*token.Token:
+			parser := NewParser(v, p.reporter)
+			parser.groupDepth = 1
+			expr, err := parser.expression()
+			if err != nil {
+				return nil, err
+			}
+			if !parser.isAtEnd() {
+				next := parser.peek()
+				return nil, p.reporter.Report(fmt.Sprintf("unexpected '%s' in string interpolation", next.Lexeme), next)
+			}
+			parts = append(parts, expr)
+		}
+	}
+	return expression.NewInterpolation(tok, parts), nil
 }
 
 func (p *Parser) primary() (expression.Expression, error) {
@@ -578,11 +1390,51 @@ func (p *Parser) primary() (expression.Expression, error) {
 		return expression.NewLiteral(p.previous().Literal), nil
 	}
 
+	if p.match(token.Interpolation) {
+		return p.interpolation()
+	}
+
 	if p.match(token.Identifier) {
 		return expression.NewVariable(p.previous()), nil
 	}
 
+	if p.match(token.Base) {
+		keyword := p.previous()
+		if _, err := p.consume(token.Dot, "expect '.' after 'base'"); err != nil {
+			return nil, err
+		}
+		method, err := p.consume(token.Identifier, "expect superclass method name")
+		if err != nil {
+			return nil, err
+		}
+		return expression.NewBase(keyword, method), nil
+	}
+
+	if p.match(token.Me) {
+		return expression.NewMe(p.previous()), nil
+	}
+
+	if p.match(token.LeftBracket) {
+		return p.list()
+	}
+
+	if p.match(token.LeftBrace) {
+		return p.mapLiteral()
+	}
+
+	if p.match(token.Func) {
+		keyword := p.previous()
+		declaration, err := p.functionBody(nil, "anonymous function")
+		if err != nil {
+			return nil, err
+		}
+		return expression.NewFunction(keyword, declaration.Params, declaration.Defaults, declaration.Rest, declaration.Body, declaration.IsGenerator), nil
+	}
+
 	if p.match(token.LeftParen) {
+		p.groupDepth++
+		defer func() { p.groupDepth-- }()
+
 		expr, err := p.expression()
 		if err != nil {
 			return nil, err
@@ -615,6 +1467,28 @@ func (p *Parser) consume(limit token.TokenType, errorMsg string) (*token.Token,
 	return nil, err
 }
 
+// consumeTerminator consumes the ';' or the new line that ends a statement. The
+// terminator can be left out before the end of a block or the end of the input.
+func (p *Parser) consumeTerminator(errorMsg string) error {
+	if p.match(token.Semicolon, token.Newline) {
+		return nil
+	}
+	if p.checkTerminator() {
+		return nil
+	}
+	_, err := p.consume(token.Semicolon, errorMsg)
+	return err
+}
+
+func (p *Parser) checkTerminator() bool {
+	return p.isAtEnd() || p.check(token.Semicolon, token.Newline, token.End, token.Else, token.Elif, token.RightBrace)
+}
+
+func (p *Parser) skipNewlines() {
+	for p.match(token.Newline) {
+	}
+}
+
 func (p *Parser) match(types ...token.TokenType) bool {
 	for _, t := range types {
 		if p.check(t) {
@@ -654,6 +1528,11 @@ func (p *Parser) isAtEnd() bool {
 }
 
 func (p *Parser) peek() *token.Token {
+	if p.groupDepth > 0 {
+		for p.tokens[p.current].Type == token.Newline {
+			p.current += 1
+		}
+	}
 	return p.tokens[p.current]
 }
 
@@ -668,7 +1547,7 @@ func (p *Parser) synchronize() {
 	p.advance()
 
 	for !p.isAtEnd() {
-		if p.previous().Type == token.Semicolon {
+		if p.previous().Type == token.Semicolon || p.previous().Type == token.Newline {
 			return
 		}
 		if p.peek().Type.IsKeyword() {
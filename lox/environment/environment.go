@@ -1,12 +1,18 @@
 package environment
 
 import (
+	"sync"
+
 	"golox/lox/token"
 )
 
+// Environment holds the variables of a scope. Closures let tasks running at the
+// same time share environments, so every access goes through a lock.
 type Environment struct {
 	enclosing *Environment
+	mu        sync.RWMutex
 	values    map[string]interface{}
+	consts    map[string]*token.Token // declarations of the constants, by name
 }
 
 func NewEnvironment(enclosing *Environment) *Environment {
@@ -16,12 +22,45 @@ func NewEnvironment(enclosing *Environment) *Environment {
 	}
 }
 
+func (env *Environment) Enclosing() *Environment {
+	return env.enclosing
+}
+
 func (env *Environment) Define(name string, value interface{}) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
 	env.values[name] = value
 }
 
+// DefineConst defines a variable that Constant reports as a constant.
+func (env *Environment) DefineConst(name *token.Token, value interface{}) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.values[name.Lexeme] = value
+	if env.consts == nil {
+		env.consts = make(map[string]*token.Token)
+	}
+	env.consts[name.Lexeme] = name
+}
+
+// Constant returns the declaration of the variable a name refers to, if that
+// variable is a constant.
+func (env *Environment) Constant(name string) (*token.Token, bool) {
+	env.mu.RLock()
+	_, found := env.values[name]
+	decl, isConst := env.consts[name]
+	env.mu.RUnlock()
+	if found {
+		return decl, isConst
+	}
+	if env.enclosing != nil {
+		return env.enclosing.Constant(name)
+	}
+	return nil, false
+}
+
 func (env *Environment) Get(name *token.Token) (interface{}, bool) {
-	v, found := env.values[name.Lexeme]
+	v, found := env.Lookup(name.Lexeme)
 	if found {
 		return v, true
 	}
@@ -31,8 +70,18 @@ func (env *Environment) Get(name *token.Token) (interface{}, bool) {
 	return nil, false
 }
 
+// Lookup returns the value of a variable defined in this environment, without
+// looking into the enclosing ones.
+func (env *Environment) Lookup(name string) (interface{}, bool) {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+	v, found := env.values[name]
+	return v, found
+}
+
 func (env *Environment) GetAt(distance int, name string) interface{} {
-	return env.ancestor(distance).values[name]
+	v, _ := env.ancestor(distance).Lookup(name)
+	return v
 }
 
 func (env *Environment) ancestor(distance int) *Environment {
@@ -44,9 +93,7 @@ func (env *Environment) ancestor(distance int) *Environment {
 }
 
 func (env *Environment) Assign(name *token.Token, value interface{}) bool {
-	_, found := env.values[name.Lexeme]
-	if found {
-		env.values[name.Lexeme] = value
+	if env.assign(name.Lexeme, value) {
 		return true
 	}
 	if env.enclosing != nil {
@@ -56,6 +103,16 @@ func (env *Environment) Assign(name *token.Token, value interface{}) bool {
 }
 
 func (env *Environment) AssignAt(distance int, name *token.Token, value interface{}) bool {
-	env.ancestor(distance).values[name.Lexeme] = value
+	env.ancestor(distance).Define(name.Lexeme, value)
+	return true
+}
+
+func (env *Environment) assign(name string, value interface{}) bool {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if _, found := env.values[name]; !found {
+		return false
+	}
+	env.values[name] = value
 	return true
 }
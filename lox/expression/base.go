@@ -0,0 +1,17 @@
+package expression
+
+import "golox/lox/token"
+
+type Base struct {
+	Keyword *token.Token
+	Method  *token.Token
+}
+
+func NewBase(keyword *token.Token, method *token.Token) *Base {
+	return &Base{
+		Keyword: keyword,
+		Method:  method,
+	}
+}
+
+func (e *Base) Expression() {}
@@ -6,13 +6,15 @@ type Call struct {
 	Callee Expression
 	Paren  *token.Token
 	Args   []Expression
+	Names  []*token.Token // one per argument, nil for positional arguments
 }
 
-func NewCall(callee Expression, paren *token.Token, args []Expression) *Call {
+func NewCall(callee Expression, paren *token.Token, args []Expression, names []*token.Token) *Call {
 	return &Call{
 		Callee: callee,
 		Paren:  paren,
 		Args:   args,
+		Names:  names,
 	}
 }
 
@@ -0,0 +1,22 @@
+package expression
+
+import "golox/lox/token"
+
+// CompoundAssign applies a binary operator to a variable, field or element and
+// stores the result back into it, as in 'x += 1'. Operator has the type of the
+// binary operator (token.Plus for '+=') but keeps the '+=' lexeme.
+type CompoundAssign struct {
+	Target   Expression // *Variable, *Get or *Index
+	Operator *token.Token
+	Value    Expression
+}
+
+func NewCompoundAssign(target Expression, operator *token.Token, val Expression) *CompoundAssign {
+	return &CompoundAssign{
+		Target:   target,
+		Operator: operator,
+		Value:    val,
+	}
+}
+
+func (e *CompoundAssign) Expression() {}
@@ -0,0 +1,30 @@
+package expression
+
+import (
+	"golox/lox/node"
+	"golox/lox/token"
+)
+
+type Function struct {
+	Keyword  *token.Token
+	Params   []*token.Token
+	Defaults []Expression // one per parameter, nil for parameters without a default
+	Rest     *token.Token // the '...rest' parameter collecting extra arguments, or nil
+	Body     []node.Stmt
+
+	IsGenerator bool // the body contains a yield statement
+}
+
+func NewFunction(keyword *token.Token, params []*token.Token, defaults []Expression, rest *token.Token, body []node.Stmt, isGenerator bool) *Function {
+	return &Function{
+		Keyword:  keyword,
+		Params:   params,
+		Defaults: defaults,
+		Rest:     rest,
+		Body:     body,
+
+		IsGenerator: isGenerator,
+	}
+}
+
+func (e *Function) Expression() {}
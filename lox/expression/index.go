@@ -0,0 +1,19 @@
+package expression
+
+import "golox/lox/token"
+
+type Index struct {
+	Object  Expression
+	Bracket *token.Token // for reporting location
+	Index   Expression
+}
+
+func NewIndex(obj Expression, bracket *token.Token, index Expression) *Index {
+	return &Index{
+		Object:  obj,
+		Bracket: bracket,
+		Index:   index,
+	}
+}
+
+func (e *Index) Expression() {}
@@ -0,0 +1,21 @@
+package expression
+
+import "golox/lox/token"
+
+type IndexSet struct {
+	Object  Expression
+	Bracket *token.Token // for reporting location
+	Index   Expression
+	Value   Expression
+}
+
+func NewIndexSet(obj Expression, bracket *token.Token, index Expression, val Expression) *IndexSet {
+	return &IndexSet{
+		Object:  obj,
+		Bracket: bracket,
+		Index:   index,
+		Value:   val,
+	}
+}
+
+func (e *IndexSet) Expression() {}
@@ -0,0 +1,19 @@
+package expression
+
+import "golox/lox/token"
+
+// Interpolation is a string literal with embedded expressions, as in
+// "hello ${name}". Parts holds the literal pieces and the expressions in order.
+type Interpolation struct {
+	Token *token.Token
+	Parts []Expression
+}
+
+func NewInterpolation(tok *token.Token, parts []Expression) *Interpolation {
+	return &Interpolation{
+		Token: tok,
+		Parts: parts,
+	}
+}
+
+func (e *Interpolation) Expression() {}
@@ -0,0 +1,17 @@
+package expression
+
+import "golox/lox/token"
+
+type List struct {
+	Bracket  *token.Token // for reporting location
+	Elements []Expression
+}
+
+func NewList(bracket *token.Token, elements []Expression) *List {
+	return &List{
+		Bracket:  bracket,
+		Elements: elements,
+	}
+}
+
+func (e *List) Expression() {}
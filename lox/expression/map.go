@@ -0,0 +1,19 @@
+package expression
+
+import "golox/lox/token"
+
+type Map struct {
+	Brace  *token.Token // for reporting location
+	Keys   []Expression
+	Values []Expression
+}
+
+func NewMap(brace *token.Token, keys []Expression, values []Expression) *Map {
+	return &Map{
+		Brace:  brace,
+		Keys:   keys,
+		Values: values,
+	}
+}
+
+func (e *Map) Expression() {}
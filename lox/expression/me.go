@@ -0,0 +1,15 @@
+package expression
+
+import "golox/lox/token"
+
+type Me struct {
+	Keyword *token.Token
+}
+
+func NewMe(keyword *token.Token) *Me {
+	return &Me{
+		Keyword: keyword,
+	}
+}
+
+func (e *Me) Expression() {}
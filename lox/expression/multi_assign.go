@@ -0,0 +1,21 @@
+package expression
+
+import "golox/lox/token"
+
+// MultiAssign assigns a list of values to a list of variables or fields, as in
+// 'a, b = b, a'.
+type MultiAssign struct {
+	Targets []Expression // *Variable, *Get or *Index
+	Equals  *token.Token
+	Values  []Expression
+}
+
+func NewMultiAssign(targets []Expression, equals *token.Token, values []Expression) *MultiAssign {
+	return &MultiAssign{
+		Targets: targets,
+		Equals:  equals,
+		Values:  values,
+	}
+}
+
+func (e *MultiAssign) Expression() {}
@@ -1,9 +0,0 @@
-package expression
-
-type NullExpr struct{}
-
-func MakeNull() NullExpr {
-	return NullExpr{}
-}
-
-func (n NullExpr) Expression() {}
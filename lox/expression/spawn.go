@@ -0,0 +1,18 @@
+package expression
+
+import "golox/lox/token"
+
+// Spawn calls a function on a task of its own and evaluates to the task.
+type Spawn struct {
+	Keyword *token.Token
+	Call    *Call
+}
+
+func NewSpawn(keyword *token.Token, call *Call) *Spawn {
+	return &Spawn{
+		Keyword: keyword,
+		Call:    call,
+	}
+}
+
+func (e *Spawn) Expression() {}
@@ -0,0 +1,18 @@
+package expression
+
+import "golox/lox/token"
+
+// Wait blocks until a task has finished and evaluates to its result.
+type Wait struct {
+	Keyword *token.Token
+	Task    Expression
+}
+
+func NewWait(keyword *token.Token, task Expression) *Wait {
+	return &Wait{
+		Keyword: keyword,
+		Task:    task,
+	}
+}
+
+func (e *Wait) Expression() {}
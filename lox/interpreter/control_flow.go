@@ -0,0 +1,14 @@
+package interpreter
+
+import "errors"
+
+// Break and continue statements unwind the interpreter up to the innermost loop
+// by returning these errors. The resolver makes sure they never escape a loop.
+var (
+	errBreak    = errors.New("'break' outside of a loop")
+	errContinue = errors.New("'continue' outside of a loop")
+)
+
+// errGeneratorClosed unwinds the body of a generator from the yield statement it
+// is suspended at when the generator is closed.
+var errGeneratorClosed = errors.New("generator closed")
@@ -0,0 +1,81 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+
+	"golox/lox/token"
+)
+
+// Exception carries a value thrown by a throw statement or a runtime error up to
+// the nearest enclosing try statement. If none catches it, Interpret reports it.
+type Exception struct {
+	Value interface{}
+	Token *token.Token // where the exception was raised
+}
+
+func NewException(value interface{}, tok *token.Token) *Exception {
+	return &Exception{
+		Value: value,
+		Token: tok,
+	}
+}
+
+func (e *Exception) Error() string {
+	if loxErr, ok := e.Value.(*LoxError); ok {
+		return loxErr.Message
+	}
+	return "uncaught exception: " + display(e.Value)
+}
+
+// LoxError is the error object runtime errors are raised with. Scripts can create
+// their own with the native Error function and read its message and line.
+type LoxError struct {
+	Message string
+	Line    int
+}
+
+func NewLoxError(message string, line int) *LoxError {
+	return &LoxError{
+		Message: message,
+		Line:    line,
+	}
+}
+
+func (e *LoxError) String() string {
+	return "error: " + e.Message
+}
+
+func (e *LoxError) Get(name *token.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "message":
+		return e.Message, nil
+	case "line":
+		return int64(e.Line), nil
+	}
+	return nil, fmt.Errorf("error has no property called '%s'", name.Lexeme)
+}
+
+// runtimeError raises a catchable runtime error at the given token.
+func (interp *Interpreter) runtimeError(msg string, tok *token.Token) error {
+	return NewException(NewLoxError(msg, tok.Line), tok)
+}
+
+// nativeError raises a runtime error from a native function, which doesn't know
+// where it was called from. The interpreter fills in the location of the call.
+func nativeError(msg string) error {
+	return NewException(NewLoxError(msg, 0), nil)
+}
+
+// locate fills in the location of a runtime error raised by native code, which
+// is the token of the call or the statement that ran it.
+func locate(err error, tok *token.Token) error {
+	var exception *Exception
+	if errors.As(err, &exception) && exception.Token == nil {
+		exception.Token = tok
+		if loxErr, ok := exception.Value.(*LoxError); ok {
+			loxErr.Line = tok.Line
+		}
+	}
+	return err
+}
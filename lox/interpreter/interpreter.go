@@ -5,31 +5,53 @@ import (
 	"fmt"
 	"golox/lox/environment"
 	"golox/lox/expression"
+	"golox/lox/pattern"
 	"golox/lox/reporter"
 	"golox/lox/statement"
 	"golox/lox/token"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
 type Interpreter struct {
 	reporter *reporter.ErrorReporter
 	env      *environment.Environment
+	builtins *environment.Environment
 	globals  *environment.Environment
 	locals   map[expression.Expression]int
 	repl     bool
+
+	loader    ModuleLoader
+	modules   map[string]*LoxModule // imported modules by absolute path
+	importing []string              // paths of the modules being imported, for cycle detection
+
+	// the interpreters of all tasks share the locals and the modules, which are
+	// added to whenever a module is imported
+	mu *sync.RWMutex
+
+	generator  *coroutine    // the generator whose body is being run, if any
+	generators *generatorSet // the started generators of all tasks
 }
 
 func NewInterpreter(reporter *reporter.ErrorReporter) *Interpreter {
 	interp := &Interpreter{
 		reporter: reporter,
-		env:      environment.NewEnvironment(nil),
+		builtins: environment.NewEnvironment(nil),
 		locals:   make(map[expression.Expression]int),
 		repl:     false,
+		modules:  make(map[string]*LoxModule),
+		mu:       &sync.RWMutex{},
+
+		generators: newGeneratorSet(),
 	}
 
-	interp.globals = interp.env
+	// every module has globals of its own, which share the built-in functions
+	interp.globals = environment.NewEnvironment(interp.builtins)
+	interp.env = interp.globals
 
-	interp.globals.Define("clock", NewLoxCallable(
+	interp.builtins.Define("clock", NewLoxCallable(
 		0,
 		func(intrp *Interpreter, args []interface{}) (interface{}, error) {
 			return time.Now().Unix(), nil
@@ -39,25 +61,117 @@ func NewInterpreter(reporter *reporter.ErrorReporter) *Interpreter {
 		}),
 	)
 
+	interp.builtins.Define("range", NewLoxVariadicCallable(
+		1, 3,
+		func(intrp *Interpreter, args []interface{}) (interface{}, error) {
+			// range(stop), range(start, stop) or range(start, stop, step)
+			bounds := []int64{0, 0, 1}
+			offset := 0
+			if len(args) == 1 {
+				offset = 1
+			}
+			for i, arg := range args {
+				n, ok := arg.(int64)
+				if !ok {
+					return nil, nativeError(fmt.Sprintf("range arguments must be integers, got '%s'", stringify(arg)))
+				}
+				bounds[i+offset] = n
+			}
+			if bounds[2] == 0 {
+				return nil, nativeError("range step can't be zero")
+			}
+			return NewLoxRange(bounds[0], bounds[1], bounds[2]), nil
+		},
+		func() string {
+			return "<native fn>"
+		}),
+	)
+
+	interp.builtins.Define("Channel", NewLoxVariadicCallable(
+		0, 1,
+		func(intrp *Interpreter, args []interface{}) (interface{}, error) {
+			// Channel() is unbuffered, Channel(n) buffers up to n values
+			capacity := int64(0)
+			if len(args) == 1 {
+				n, ok := args[0].(int64)
+				if !ok || n < 0 {
+					return nil, nativeError(fmt.Sprintf("channel capacity must be a non-negative integer, got '%s'", stringify(args[0])))
+				}
+				capacity = n
+			}
+			return NewLoxChannel(int(capacity)), nil
+		},
+		func() string {
+			return "<native fn>"
+		}),
+	)
+
+	interp.builtins.Define("Error", NewLoxCallable(
+		1,
+		func(intrp *Interpreter, args []interface{}) (interface{}, error) {
+			return NewLoxError(display(args[0]), 0), nil
+		},
+		func() string {
+			return "<native fn>"
+		}),
+	)
+
 	return interp
 }
 
 func (interp *Interpreter) Interpret(statements []statement.Stmt, repl bool) error {
 	interp.repl = repl
+	// nothing can resume a generator once the script has finished, the prompt
+	// keeps them for the lines that follow
+	if !repl {
+		defer interp.generators.stopAll()
+	}
 	for _, stmt := range statements {
-		_, err := interp.execute(stmt)
-		if err != nil {
+		if err := interp.execute(stmt); err != nil {
+			var exception *Exception
+			if errors.As(err, &exception) {
+				if exception.Token == nil {
+					// native code raised it somewhere nothing could locate it
+					fmt.Println(exception.Error())
+				} else {
+					interp.reporter.Report(exception.Error(), exception.Token)
+				}
+			}
 			return err
 		}
 	}
 	return nil
 }
 
+func (interp *Interpreter) SetModuleLoader(loader ModuleLoader) {
+	interp.loader = loader
+}
+
 func (interp *Interpreter) Resolve(expr expression.Expression, depth int) {
+	interp.mu.Lock()
+	defer interp.mu.Unlock()
 	interp.locals[expr] = depth
 }
 
-func (interp *Interpreter) execute(stmt statement.Stmt) (interface{}, error) {
+// localDepth returns the number of scopes between a resolved local variable and
+// the place it is used. Global variables aren't resolved.
+func (interp *Interpreter) localDepth(expr expression.Expression) (int, bool) {
+	interp.mu.RLock()
+	defer interp.mu.RUnlock()
+	distance, found := interp.locals[expr]
+	return distance, found
+}
+
+// fork returns an interpreter for running code on another goroutine, which has
+// an environment of its own and shares everything else with interp.
+func (interp *Interpreter) fork() *Interpreter {
+	fork := *interp
+	fork.importing = append([]string(nil), interp.importing...)
+	fork.generator = nil
+	return &fork
+}
+
+func (interp *Interpreter) execute(stmt statement.Stmt) error {
 	switch v := stmt.(type) {
 	case *statement.PrintStmt:
 		return interp.executePrintStmt(v)
@@ -67,14 +181,36 @@ func (interp *Interpreter) execute(stmt statement.Stmt) (interface{}, error) {
 		return interp.executeFuncStmt(v)
 	case *statement.VarStmt:
 		return interp.executeVarStmt(v)
+	case *statement.ConstStmt:
+		return interp.executeConstStmt(v)
 	case *statement.BlockStmt:
 		return interp.executeBlockStmt(v)
 	case *statement.IfStmt:
 		return interp.executeIfStmt(v)
 	case *statement.WhileStmt:
 		return interp.executeWhileStmt(v)
+	case *statement.ForInStmt:
+		return interp.executeForInStmt(v)
+	case *statement.NumericForStmt:
+		return interp.executeNumericForStmt(v)
+	case *statement.RepeatStmt:
+		return interp.executeRepeatStmt(v)
+	case *statement.MatchStmt:
+		return interp.executeMatchStmt(v)
 	case *statement.ReturnStmt:
 		return interp.executeReturnStmt(v)
+	case *statement.BreakStmt:
+		return errBreak
+	case *statement.ContinueStmt:
+		return errContinue
+	case *statement.TryStmt:
+		return interp.executeTryStmt(v)
+	case *statement.ThrowStmt:
+		return interp.executeThrowStmt(v)
+	case *statement.YieldStmt:
+		return interp.executeYieldStmt(v)
+	case *statement.ImportStmt:
+		return interp.executeImportStmt(v)
 	case *statement.ClassStmt:
 		return interp.executeClassStmt(v)
 	default:
@@ -82,127 +218,476 @@ func (interp *Interpreter) execute(stmt statement.Stmt) (interface{}, error) {
 	}
 }
 
-func (interp *Interpreter) executeVarStmt(stmt *statement.VarStmt) (interface{}, error) {
+func (interp *Interpreter) executeVarStmt(stmt *statement.VarStmt) error {
 	// this part forbids shadowing variable names
-	if _, defined := interp.env.Get(stmt.Name); defined {
-		err := interp.reporter.Report(fmt.Sprintf("variable named '%s' already exists", stmt.Name.Lexeme), stmt.Name)
-		return nil, err
+	for _, name := range stmt.Names {
+		if _, defined := interp.env.Get(name); defined {
+			err := interp.runtimeError(fmt.Sprintf("variable named '%s' already exists", name.Lexeme), name)
+			return err
+		}
 	}
 
-	var (
-		val interface{}
-		err error
-	)
-	if stmt.Initializer != nil {
-		if val, err = interp.evaluate(stmt.Initializer); err != nil {
+	values, err := interp.varValues(stmt)
+	if err != nil {
+		return err
+	}
+	for i, name := range stmt.Names {
+		interp.env.Define(name.Lexeme, values[i])
+	}
+	return nil
+}
+
+// varValues evaluates the initializers of a variable declaration, giving one value
+// per declared name. Variables without an initializer are null.
+func (interp *Interpreter) varValues(stmt *statement.VarStmt) ([]interface{}, error) {
+	values := make([]interface{}, len(stmt.Names))
+	if len(stmt.Names) == 1 && len(stmt.Initializers) == 1 {
+		// a single variable takes the first value of a call, like any other single
+		// value context
+		val, err := interp.evaluate(stmt.Initializers[0])
+		if err != nil {
 			return nil, err
 		}
+		values[0] = val
+	} else if stmt.Initializers != nil {
+		vals, err := interp.evaluateValues(stmt.Initializers)
+		if err != nil {
+			return nil, err
+		}
+		if len(vals) != len(stmt.Names) {
+			return nil, interp.runtimeError(fmt.Sprintf("expect %d values in variable declaration but got %d", len(stmt.Names), len(vals)), stmt.Names[0])
+		}
+		values = vals
 	}
-	interp.env.Define(stmt.Name.Lexeme, val)
-	return nil, nil
+	return values, nil
+}
+
+func (interp *Interpreter) executeConstStmt(stmt *statement.ConstStmt) error {
+	if _, defined := interp.env.Get(stmt.Name); defined {
+		return interp.runtimeError(fmt.Sprintf("variable named '%s' already exists", stmt.Name.Lexeme), stmt.Name)
+	}
+	val, err := interp.evaluate(stmt.Initializer)
+	if err != nil {
+		return err
+	}
+	interp.env.DefineConst(stmt.Name, val)
+	return nil
 }
 
-func (interp *Interpreter) executePrintStmt(stmt *statement.PrintStmt) (interface{}, error) {
+func (interp *Interpreter) executePrintStmt(stmt *statement.PrintStmt) error {
 	val, err := interp.evaluate(stmt.Expression)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	fmt.Printf("%v\n", val)
-	return nil, nil
+	fmt.Println(display(val))
+	return nil
 }
 
-func (interp *Interpreter) executeExprStmt(stmt *statement.ExpressionStmt) (interface{}, error) {
+func (interp *Interpreter) executeExprStmt(stmt *statement.ExpressionStmt) error {
 	val, err := interp.evaluate(stmt.Expression)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if interp.repl {
 		fmt.Println("eval:", val)
 	}
-	return nil, nil
+	return nil
 }
 
-func (interp *Interpreter) executeFuncStmt(stmt *statement.FunctionStmt) (interface{}, error) {
-	function := NewLoxFunction(stmt, interp.env)
+func (interp *Interpreter) executeFuncStmt(stmt *statement.FunctionStmt) error {
+	function := NewLoxFunction(stmt, interp.env, false)
 	interp.env.Define(stmt.Name.Lexeme, function)
-	return nil, nil
+	return nil
 }
 
-func (interp *Interpreter) executeBlockStmt(stmt *statement.BlockStmt) (interface{}, error) {
+func (interp *Interpreter) executeBlockStmt(stmt *statement.BlockStmt) error {
 	env := environment.NewEnvironment(interp.env)
 	return interp.executeBlock(stmt.Statements, env)
 }
 
-// TODO: Implement ReturnValue struct which holds an array of return values.
-// We should also support multiple return values.
-// Implement NewLine token, so that we can ommit ';' from most of the code base.
-func (interp *Interpreter) executeBlock(statements []statement.Stmt, env *environment.Environment) (interface{}, error) {
+func (interp *Interpreter) executeBlock(statements []statement.Stmt, env *environment.Environment) error {
 	previous := interp.env
 	defer interp.setEnvironment(previous)
 	interp.env = env
 
 	for _, s := range statements {
-		retval, err := interp.execute(s)
-		if err != nil {
-			return nil, err
-		}
-		// TODO: Potential rework. Make return statements more elegant.
-		if retval != nil {
-			return retval, nil
+		if err := interp.execute(s); err != nil {
+			return err
 		}
 	}
 
-	return nil, nil
+	return nil
 }
 
-func (interp *Interpreter) executeIfStmt(stmt *statement.IfStmt) (interface{}, error) {
+func (interp *Interpreter) executeIfStmt(stmt *statement.IfStmt) error {
 	cond, err := interp.evaluate(stmt.Condition)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if isTruthy(cond) {
 		return interp.execute(stmt.ThenBranch)
-	} else if stmt.ElseBranch != nil {
+	}
+	for _, elif := range stmt.ElifBranches {
+		cond, err := interp.evaluate(elif.Condition)
+		if err != nil {
+			return err
+		}
+		if isTruthy(cond) {
+			return interp.execute(elif.ThenBranch)
+		}
+	}
+	if stmt.ElseBranch != nil {
 		return interp.execute(stmt.ElseBranch)
 	}
-	return nil, nil
+	return nil
 }
 
-func (interp *Interpreter) executeWhileStmt(stmt *statement.WhileStmt) (interface{}, error) {
+func (interp *Interpreter) executeWhileStmt(stmt *statement.WhileStmt) error {
 	for {
 		cond, err := interp.evaluate(stmt.Condition)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if !isTruthy(cond) {
+			return nil
+		}
+
+		err = interp.execute(stmt.Body)
+		if errors.Is(err, errBreak) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, errContinue) {
+			return err
+		}
+
+		if stmt.Increment != nil {
+			if _, err := interp.evaluate(stmt.Increment); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (interp *Interpreter) executeForInStmt(stmt *statement.ForInStmt) error {
+	iterable, err := interp.evaluate(stmt.Iterable)
+	if err != nil {
+		return err
+	}
+	next, err := interp.iterate(iterable, len(stmt.Names), stmt.In)
+	if err != nil {
+		return err
+	}
+
+	return interp.loop(stmt.Names, next, stmt.Body)
+}
+
+func (interp *Interpreter) executeNumericForStmt(stmt *statement.NumericForStmt) error {
+	bounds := []expression.Expression{stmt.Start, stmt.Stop}
+	if stmt.Step != nil {
+		bounds = append(bounds, stmt.Step)
+	}
+	values := []interface{}{nil, nil, int64(1)}
+	for i, bound := range bounds {
+		val, err := interp.evaluate(bound)
+		if err != nil {
+			return err
+		}
+		if !isNumber(val) {
+			return interp.runtimeError(fmt.Sprintf("for loop bounds must be numbers, got '%s'", stringify(val)), stmt.Name)
+		}
+		values[i] = val
+	}
+	if toFloat(values[2]) == 0 {
+		return interp.runtimeError("for loop step can't be zero", stmt.Name)
+	}
+
+	return interp.loop([]*token.Token{stmt.Name}, numericSteps(values[0], values[1], values[2]), stmt.Body)
+}
+
+// loop runs the body of a for-in or numeric for loop once for every step of the
+// iterator.
+func (interp *Interpreter) loop(names []*token.Token, next iterator, body statement.Stmt) error {
+	for {
+		values, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		// every step gets its own environment, so closures capture that step's values
+		env := environment.NewEnvironment(interp.env)
+		for i, name := range names {
+			env.Define(name.Lexeme, values[i])
+		}
+		err = interp.executeBlock([]statement.Stmt{body}, env)
+		if errors.Is(err, errBreak) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, errContinue) {
+			return err
+		}
+	}
+}
+
+func (interp *Interpreter) executeRepeatStmt(stmt *statement.RepeatStmt) error {
+	for {
+		env := environment.NewEnvironment(interp.env)
+		err := interp.executeBlock(stmt.Body, env)
+		if errors.Is(err, errBreak) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, errContinue) {
+			return err
+		}
+
+		// the condition is evaluated in the body's environment
+		cond, err := interp.evaluateIn(stmt.Condition, env)
+		if err != nil {
+			return err
 		}
 		if isTruthy(cond) {
-			if _, err := interp.execute(stmt.Body); err != nil {
-				return nil, err
+			return nil
+		}
+	}
+}
+
+func (interp *Interpreter) executeMatchStmt(stmt *statement.MatchStmt) error {
+	value, err := interp.evaluate(stmt.Value)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range stmt.Cases {
+		// a case binds its names in an environment of its own, which is thrown away
+		// if the pattern or the guard doesn't match
+		env := environment.NewEnvironment(interp.env)
+		matched, err := interp.matchPattern(c.Pattern, value, env)
+		if err != nil {
+			return err
+		}
+		if matched && c.Guard != nil {
+			guard, err := interp.evaluateIn(c.Guard, env)
+			if err != nil {
+				return err
 			}
-			continue
+			matched = isTruthy(guard)
+		}
+		if matched {
+			return interp.executeBlock(c.Body, env)
 		}
-		return nil, nil
 	}
+
+	return interp.runtimeError(fmt.Sprintf("no case matches '%s'", stringify(value)), stmt.Keyword)
 }
 
-func (interp *Interpreter) executeReturnStmt(stmt *statement.ReturnStmt) (interface{}, error) {
-	var (
-		val interface{} = nil
-		err error
-	)
+func (interp *Interpreter) matchPattern(pat pattern.Pattern, value interface{}, env *environment.Environment) (bool, error) {
+	switch p := pat.(type) {
+	case *pattern.Wildcard:
+		return true, nil
+	case *pattern.Binding:
+		env.Define(p.Name.Lexeme, value)
+		return true, nil
+	case *pattern.Literal:
+		// the same as 'value == literal', which can call the value's '__eq'
+		return interp.equal(p.Token, value, p.Value)
+	case *pattern.Instance:
+		// the class is resolved in the scope of the case
+		class, err := interp.evaluateIn(p.Class, env)
+		if err != nil {
+			return false, err
+		}
+		loxClass, ok := class.(*LoxClass)
+		if !ok {
+			return false, interp.runtimeError(fmt.Sprintf("'%s' in a class pattern is not a class", p.Class.Name.Lexeme), p.Class.Name)
+		}
+		instance, ok := value.(*LoxInstance)
+		if !ok || !instance.IsInstanceOf(loxClass) {
+			return false, nil
+		}
+		for i, field := range p.Fields {
+			fieldValue, found := instance.field(field.Lexeme)
+			if !found {
+				return false, nil
+			}
+			matched, err := interp.matchPattern(p.Patterns[i], fieldValue, env)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
 
-	if stmt.Value != nil {
-		if val, err = interp.evaluate(stmt.Value); err != nil {
-			return nil, err
+func (interp *Interpreter) executeReturnStmt(stmt *statement.ReturnStmt) error {
+	values, err := interp.evaluateValues(stmt.Values)
+	if err != nil {
+		return err
+	}
+	return NewReturnValue(values)
+}
+
+func (interp *Interpreter) executeTryStmt(stmt *statement.TryStmt) error {
+	err := interp.executeBlock(stmt.Body, environment.NewEnvironment(interp.env))
+
+	// only exceptions are caught, returns and loop jumps pass through
+	var exception *Exception
+	if stmt.CatchBody != nil && errors.As(err, &exception) {
+		env := environment.NewEnvironment(interp.env)
+		if stmt.CatchName != nil {
+			env.Define(stmt.CatchName.Lexeme, exception.Value)
 		}
+		err = interp.executeBlock(stmt.CatchBody, env)
 	}
-	return val, nil
+
+	if stmt.FinallyBody != nil {
+		// an error raised by the finally block replaces the pending one
+		if finallyErr := interp.executeBlock(stmt.FinallyBody, environment.NewEnvironment(interp.env)); finallyErr != nil {
+			return finallyErr
+		}
+	}
+
+	return err
 }
 
-func (interp *Interpreter) executeClassStmt(stmt *statement.ClassStmt) (interface{}, error) {
+func (interp *Interpreter) executeThrowStmt(stmt *statement.ThrowStmt) error {
+	val, err := interp.evaluate(stmt.Value)
+	if err != nil {
+		return err
+	}
+	if loxErr, ok := val.(*LoxError); ok && loxErr.Line == 0 {
+		loxErr.Line = stmt.Keyword.Line
+	}
+	return NewException(val, stmt.Keyword)
+}
+
+func (interp *Interpreter) executeYieldStmt(stmt *statement.YieldStmt) error {
+	val, err := interp.evaluate(stmt.Value)
+	if err != nil {
+		return err
+	}
+	return interp.generator.yield(val)
+}
+
+func (interp *Interpreter) executeImportStmt(stmt *statement.ImportStmt) error {
+	module, err := interp.importModule(stmt)
+	if err != nil {
+		return err
+	}
+	if _, defined := interp.env.Get(stmt.Name); defined {
+		return interp.runtimeError(fmt.Sprintf("variable named '%s' already exists", stmt.Name.Lexeme), stmt.Name)
+	}
+	interp.env.Define(stmt.Name.Lexeme, module)
+	return nil
+}
+
+// importModule runs the module the first time it is imported and returns it from
+// the cache afterwards. The path is relative to the file of the importing script.
+func (interp *Interpreter) importModule(stmt *statement.ImportStmt) (*LoxModule, error) {
+	path := stmt.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(stmt.Keyword.File), path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, interp.runtimeError(fmt.Sprintf("invalid module path '%s': %v", stmt.Path, err), stmt.Keyword)
+	}
+
+	interp.mu.RLock()
+	module, found := interp.modules[path]
+	interp.mu.RUnlock()
+	if found {
+		return module, nil
+	}
+	for i, importing := range interp.importing {
+		if importing == path {
+			cycle := make([]string, 0)
+			for _, p := range append(interp.importing[i:], path) {
+				cycle = append(cycle, filepath.Base(p))
+			}
+			return nil, interp.runtimeError(fmt.Sprintf("import cycle: %s", strings.Join(cycle, " -> ")), stmt.Keyword)
+		}
+	}
+	if interp.loader == nil {
+		return nil, interp.runtimeError("modules can't be imported here", stmt.Keyword)
+	}
+
+	statements, err := interp.loader(path)
+	if err != nil {
+		return nil, interp.runtimeError(fmt.Sprintf("can't import '%s': %v", stmt.Path, err), stmt.Keyword)
+	}
+
+	interp.importing = append(interp.importing, path)
+	defer func() { interp.importing = interp.importing[:len(interp.importing)-1] }()
+
+	env := environment.NewEnvironment(interp.builtins)
+	if err := interp.executeBlock(statements, env); err != nil {
+		return nil, err
+	}
+
+	module = NewLoxModule(stmt.Name.Lexeme, env)
+	interp.mu.Lock()
+	interp.modules[path] = module
+	interp.mu.Unlock()
+	return module, nil
+}
+
+func (interp *Interpreter) executeClassStmt(stmt *statement.ClassStmt) error {
+	var superclass *LoxClass
+	if stmt.Superclass != nil {
+		val, err := interp.evaluate(stmt.Superclass)
+		if err != nil {
+			return err
+		}
+		class, ok := val.(*LoxClass)
+		if !ok {
+			return interp.runtimeError(fmt.Sprintf("superclass '%s' must be a class", stmt.Superclass.Name.Lexeme), stmt.Superclass.Name)
+		}
+		superclass = class
+	}
+
 	interp.env.Define(stmt.Name.Lexeme, nil)
-	class := NewLoxClass(stmt.Name.Lexeme)
+
+	// static methods have no 'me' or 'base', so they close over the outer environment
+	staticMethods := make(map[string]*LoxFunction)
+	for _, method := range stmt.StaticMethods {
+		staticMethods[method.Name.Lexeme] = NewLoxFunction(method, interp.env, false)
+	}
+
+	if superclass != nil {
+		interp.env = environment.NewEnvironment(interp.env)
+		interp.env.Define("base", superclass)
+	}
+
+	methods := make(map[string]*LoxFunction)
+	for _, method := range stmt.Methods {
+		methods[method.Name.Lexeme] = NewLoxFunction(method, interp.env, method.Name.Lexeme == "init")
+	}
+	getters := make(map[string]*LoxFunction)
+	for _, getter := range stmt.Getters {
+		getters[getter.Name.Lexeme] = NewLoxFunction(getter, interp.env, false)
+	}
+	class := NewLoxClass(stmt.Name.Lexeme, superclass, methods, staticMethods, getters)
+
+	if superclass != nil {
+		interp.env = interp.env.Enclosing()
+	}
+
 	interp.env.Assign(stmt.Name, class)
-	return nil, nil
+
+	// class level variables are initialized once the class exists, so they can use it
+	for _, field := range stmt.Fields {
+		values, err := interp.varValues(field)
+		if err != nil {
+			return err
+		}
+		for i, name := range field.Names {
+			class.Set(name, values[i])
+		}
+	}
+	return nil
 }
 
 func (interp *Interpreter) evaluate(expr expression.Expression) (interface{}, error) {
@@ -219,20 +704,93 @@ func (interp *Interpreter) evaluate(expr expression.Expression) (interface{}, er
 		return interp.evaluateVariableExpr(v)
 	case *expression.Assign:
 		return interp.evaluateAssignExpr(v)
+	case *expression.MultiAssign:
+		return interp.evaluateMultiAssignExpr(v)
+	case *expression.CompoundAssign:
+		return interp.evaluateCompoundAssignExpr(v)
 	case *expression.Logical:
 		return interp.evaluateLogicalExpr(v)
 	case *expression.Call:
 		return interp.evaluateCallExpr(v)
+	case *expression.Spawn:
+		return interp.evaluateSpawnExpr(v)
+	case *expression.Wait:
+		return interp.evaluateWaitExpr(v)
 	case *expression.Get:
 		return interp.evaluateGetExpr(v)
 	case *expression.Set:
 		return interp.evaluateSetExpr(v)
+	case *expression.Me:
+		return interp.evaluateMeExpr(v)
+	case *expression.Base:
+		return interp.evaluateBaseExpr(v)
+	case *expression.Function:
+		return interp.evaluateFunctionExpr(v)
+	case *expression.List:
+		return interp.evaluateListExpr(v)
+	case *expression.Map:
+		return interp.evaluateMapExpr(v)
+	case *expression.Interpolation:
+		return interp.evaluateInterpolationExpr(v)
+	case *expression.Index:
+		return interp.evaluateIndexExpr(v)
+	case *expression.IndexSet:
+		return interp.evaluateIndexSetExpr(v)
 	default:
 		fmt.Println("unknown expression type")
 	}
 	return nil, nil
 }
 
+// evaluateIn evaluates an expression in the given environment instead of the
+// current one.
+func (interp *Interpreter) evaluateIn(expr expression.Expression, env *environment.Environment) (interface{}, error) {
+	previous := interp.env
+	defer interp.setEnvironment(previous)
+	interp.env = env
+	return interp.evaluate(expr)
+}
+
+// evaluateValues evaluates the right hand side of a return statement, a variable
+// declaration or a multiple assignment. A single call expression expands to all of
+// the values returned by the call, and so does waiting for a task.
+func (interp *Interpreter) evaluateValues(exprs []expression.Expression) ([]interface{}, error) {
+	if len(exprs) == 1 {
+		var (
+			result   interface{}
+			err      error
+			expanded = true
+		)
+		switch v := exprs[0].(type) {
+		case *expression.Call:
+			result, err = interp.call(v)
+		case *expression.Wait:
+			result, err = interp.wait(v)
+		default:
+			expanded = false
+		}
+		if err != nil {
+			return nil, err
+		}
+		if expanded {
+			if retval, ok := result.(*ReturnValue); ok {
+				return retval.Values, nil
+			}
+			return []interface{}{result}, nil
+		}
+	}
+
+	values := make([]interface{}, 0, len(exprs))
+	for _, expr := range exprs {
+		val, err := interp.evaluate(expr)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, val)
+	}
+	return values, nil
+}
+
 func (interp *Interpreter) evaluateLiteralExpr(expr *expression.Literal) (interface{}, error) {
 	return expr.Value, nil
 }
@@ -247,9 +805,14 @@ func (interp *Interpreter) evaluateUnaryExpr(expr *expression.Unary) (interface{
 	case token.Bang, token.Not:
 		return !isTruthy(right), nil
 	case token.Minus:
+		if result, overloaded, err := interp.callSpecial(expr.Operator, right, "__neg"); overloaded || err != nil {
+			return result, err
+		}
 		if err := interp.checkNumberOperand(expr.Operator, right); err != nil {
-			interp.reporter.Report(err.Error(), expr.Operator)
-			break
+			return nil, err
+		}
+		if i, ok := right.(int64); ok {
+			return -i, nil
 		}
 		return -right.(float64), nil
 	}
@@ -266,57 +829,41 @@ func (interp *Interpreter) evaluateBinaryExpr(expr *expression.Binary) (interfac
 	if err != nil {
 		return nil, err
 	}
+	return interp.binary(expr.Operator, left, right)
+}
 
-	switch expr.Operator.Type {
-	case token.Greater:
-		if err := interp.checkNumberOperands(expr.Operator, left, right); err != nil {
-			return nil, err
-		}
-		return left.(float64) > right.(float64), nil
-	case token.GreaterEqual:
-		if err := interp.checkNumberOperands(expr.Operator, left, right); err != nil {
-			return nil, err
-		}
-		return left.(float64) >= right.(float64), nil
-	case token.Less:
-		if err := interp.checkNumberOperands(expr.Operator, left, right); err != nil {
-			return nil, err
-		}
-		return left.(float64) < right.(float64), nil
-	case token.LessEqual:
-		if err := interp.checkNumberOperands(expr.Operator, left, right); err != nil {
+// binary applies a binary operator to operands that have already been evaluated.
+func (interp *Interpreter) binary(operator *token.Token, left, right interface{}) (interface{}, error) {
+	if result, overloaded, err := interp.overloadBinary(operator, left, right); overloaded || err != nil {
+		return result, err
+	}
+
+	switch operator.Type {
+	case token.Greater, token.GreaterEqual, token.Less, token.LessEqual:
+		if err := interp.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
-		return left.(float64) <= right.(float64), nil
+		return orderNumbers(operator, left, right), nil
 	case token.EqualEqual:
-		if err := interp.checkEqualityOperands(expr.Operator, left, right); err != nil {
+		if err := interp.checkEqualityOperands(operator, left, right); err != nil {
 			return nil, err
 		}
-		return isEqual(left, right), nil
+		return interp.equalValues(operator, left, right)
 	case token.BangEqual:
-		return !isEqual(left, right), nil
-	case token.Minus:
-		if err := interp.checkNumberOperands(expr.Operator, left, right); err != nil {
-			return nil, err
-		}
-		return left.(float64) - right.(float64), nil
-	case token.Plus:
-		if err := interp.checkNumberOperands(expr.Operator, left, right); err != nil {
-			return nil, err
-		}
-		return left.(float64) + right.(float64), nil
-	case token.Slash:
-		if err := interp.checkNumberOperands(expr.Operator, left, right); err != nil {
+		eq, err := interp.equalValues(operator, left, right)
+		return !eq, err
+	case token.Minus, token.Plus, token.Slash, token.Star, token.Div, token.Percent, token.StarStar:
+		if err := interp.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
-		return left.(float64) / right.(float64), nil
-	case token.Star:
-		if err := interp.checkNumberOperands(expr.Operator, left, right); err != nil {
+		return arithmetic(operator, left, right), nil
+	case token.Ampersand, token.Pipe, token.Caret, token.LessLess, token.GreaterGreater:
+		if err := interp.checkIntegerOperands(operator, left, right); err != nil {
 			return nil, err
 		}
-		return left.(float64) * right.(float64), nil
+		return bitwise(operator, left.(int64), right.(int64)), nil
 	case token.DotDot:
-		if err := interp.checkStringOperands(expr.Operator, left, right); err != nil {
+		if err := interp.checkStringOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 		return left.(string) + right.(string), nil
@@ -334,13 +881,14 @@ func (interp *Interpreter) evaluateVariableExpr(expr *expression.Variable) (inte
 }
 
 func (interp *Interpreter) lookUpVariable(name *token.Token, expr expression.Expression) (interface{}, error) {
-	if distance, found := interp.locals[expr]; found {
+	if distance, found := interp.localDepth(expr); found {
 		return interp.env.GetAt(distance, name.Lexeme), nil
 	}
-	if glob, found := interp.globals.Get(name); found {
+	// unresolved variables are globals, which the environment chain always ends with
+	if glob, found := interp.env.Get(name); found {
 		return glob, nil
 	}
-	return nil, interp.reporter.Report(fmt.Sprintf("undefined variable '%s'", name.Lexeme), name)
+	return nil, interp.runtimeError(fmt.Sprintf("undefined variable '%s'", name.Lexeme), name)
 
 }
 
@@ -349,14 +897,148 @@ func (interp *Interpreter) evaluateAssignExpr(expr *expression.Assign) (interfac
 	if err != nil {
 		return nil, err
 	}
+	if err := interp.assignVariable(expr, expr.Name, val); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
 
-	if distance, found := interp.locals[expr]; found {
-		interp.env.AssignAt(distance, expr.Name, val)
-	} else {
-		interp.globals.Assign(expr.Name, val)
+// assignVariable assigns an existing variable. The resolver rejects assignments
+// to local constants, global ones are only known here.
+func (interp *Interpreter) assignVariable(expr expression.Expression, name *token.Token, val interface{}) error {
+	if distance, found := interp.localDepth(expr); found {
+		interp.env.AssignAt(distance, name, val)
+		return nil
+	}
+	if decl, isConst := interp.env.Constant(name.Lexeme); isConst {
+		return interp.runtimeError(ConstantAssignmentMessage(name, decl), name)
+	}
+	if !interp.env.Assign(name, val) {
+		return interp.runtimeError(fmt.Sprintf("can't assign to undefined variable '%s'", name.Lexeme), name)
 	}
+	return nil
+}
 
-	return val, nil
+// ConstantAssignmentMessage describes an assignment to the constant declared at
+// decl, pointing at the declaration.
+func ConstantAssignmentMessage(name, decl *token.Token) string {
+	where := fmt.Sprintf("line %d", decl.Line)
+	if decl.File != name.File {
+		where = fmt.Sprintf("%s:%d", decl.File, decl.Line)
+	}
+	return fmt.Sprintf("can't assign to constant '%s', declared at %s", name.Lexeme, where)
+}
+
+// evaluateCompoundAssignExpr handles 'x += y' and friends. The object and index of
+// the target are evaluated only once, so 'xs[next()] += 1' calls next once.
+// The target is read and written in two steps, which tasks can interleave.
+func (interp *Interpreter) evaluateCompoundAssignExpr(expr *expression.CompoundAssign) (interface{}, error) {
+	switch t := expr.Target.(type) {
+	case *expression.Variable:
+		current, err := interp.lookUpVariable(t.Name, t)
+		if err != nil {
+			return nil, err
+		}
+		val, err := interp.compound(expr, current)
+		if err != nil {
+			return nil, err
+		}
+		if err := interp.assignVariable(t, t.Name, val); err != nil {
+			return nil, err
+		}
+		return val, nil
+	case *expression.Get:
+		object, err := interp.evaluate(t.Object)
+		if err != nil {
+			return nil, err
+		}
+		current, err := interp.getProperty(object, t.Name)
+		if err != nil {
+			return nil, err
+		}
+		val, err := interp.compound(expr, current)
+		if err != nil {
+			return nil, err
+		}
+		if err := interp.setProperty(object, t.Name, val); err != nil {
+			return nil, err
+		}
+		return val, nil
+	case *expression.Index:
+		object, err := interp.evaluate(t.Object)
+		if err != nil {
+			return nil, err
+		}
+		index, err := interp.evaluate(t.Index)
+		if err != nil {
+			return nil, err
+		}
+		current, err := interp.getIndex(object, index, t.Bracket)
+		if err != nil {
+			return nil, err
+		}
+		val, err := interp.compound(expr, current)
+		if err != nil {
+			return nil, err
+		}
+		if err := interp.setIndex(object, index, val, t.Bracket); err != nil {
+			return nil, err
+		}
+		return val, nil
+	}
+	return nil, interp.runtimeError("invalid assignment target", expr.Operator)
+}
+
+// compound evaluates the right hand side of a compound assignment and combines it
+// with the current value of the target.
+func (interp *Interpreter) compound(expr *expression.CompoundAssign, current interface{}) (interface{}, error) {
+	val, err := interp.evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	return interp.binary(expr.Operator, current, val)
+}
+
+func (interp *Interpreter) evaluateMultiAssignExpr(expr *expression.MultiAssign) (interface{}, error) {
+	values, err := interp.evaluateValues(expr.Values)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != len(expr.Targets) {
+		err = interp.runtimeError(fmt.Sprintf("expect %d values in assignment but got %d", len(expr.Targets), len(values)), expr.Equals)
+		return nil, err
+	}
+
+	// targets are evaluated after all of the values, so 'a, b = b, a' swaps them
+	for i, target := range expr.Targets {
+		switch t := target.(type) {
+		case *expression.Variable:
+			if err := interp.assignVariable(t, t.Name, values[i]); err != nil {
+				return nil, err
+			}
+		case *expression.Get:
+			object, err := interp.evaluate(t.Object)
+			if err != nil {
+				return nil, err
+			}
+			if err := interp.setProperty(object, t.Name, values[i]); err != nil {
+				return nil, err
+			}
+		case *expression.Index:
+			object, err := interp.evaluate(t.Object)
+			if err != nil {
+				return nil, err
+			}
+			index, err := interp.evaluate(t.Index)
+			if err != nil {
+				return nil, err
+			}
+			if err := interp.setIndex(object, index, values[i], t.Bracket); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, nil
 }
 
 func (interp *Interpreter) evaluateLogicalExpr(expr *expression.Logical) (interface{}, error) {
@@ -384,32 +1066,109 @@ func (interp *Interpreter) evaluateLogicalExpr(expr *expression.Logical) (interf
 }
 
 func (interp *Interpreter) evaluateCallExpr(expr *expression.Call) (interface{}, error) {
-	callee, err := interp.evaluate(expr.Callee)
+	result, err := interp.call(expr)
+	if err != nil {
+		return nil, err
+	}
+	// only the first of multiple return values is used in a single value context
+	if retval, ok := result.(*ReturnValue); ok {
+		return retval.First(), nil
+	}
+	return result, nil
+}
+
+func (interp *Interpreter) call(expr *expression.Call) (interface{}, error) {
+	function, args, err := interp.callee(expr)
 	if err != nil {
 		return nil, err
 	}
+	return interp.callFunction(function, args, expr.Paren)
+}
+
+// callee evaluates the function and the arguments of a call, with the named
+// arguments put in place.
+func (interp *Interpreter) callee(expr *expression.Call) (LoxCallable, []interface{}, error) {
+	callee, err := interp.evaluate(expr.Callee)
+	if err != nil {
+		return nil, nil, err
+	}
 	args := make([]interface{}, 0)
-	for _, arg := range expr.Args {
+	namedArgs := make(map[*token.Token]interface{})
+	for i, arg := range expr.Args {
 		val, err := interp.evaluate(arg)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		if expr.Names[i] != nil {
+			namedArgs[expr.Names[i]] = val
+		} else {
+			args = append(args, val)
 		}
-		args = append(args, val)
 	}
 
 	function, ok := callee.(LoxCallable)
 	if !ok {
-		err = interp.reporter.Report(fmt.Sprintf("'%v' is not a callable function or a class", callee), expr.Paren)
-		return nil, err
+		err = interp.runtimeError(fmt.Sprintf("'%v' is not a callable function or a class", callee), expr.Paren)
+		return nil, nil, err
+	}
+
+	if len(namedArgs) > 0 {
+		if args, err = interp.placeNamedArgs(function, args, expr.Names, namedArgs, expr.Paren); err != nil {
+			return nil, nil, err
+		}
 	}
+	return function, args, nil
+}
 
-	if len(args) != function.Arity() {
-		err = interp.reporter.Report(fmt.Sprintf("expect %d arguments but got %d", function.Arity(), len(args)), expr.Paren)
+// callFunction checks the number of arguments and calls the function. Errors
+// raised by native functions are located at tok.
+func (interp *Interpreter) callFunction(function LoxCallable, args []interface{}, tok *token.Token) (interface{}, error) {
+	if err := interp.checkArity(function, len(args), tok); err != nil {
 		return nil, err
 	}
 
-	return function.Call(interp, args)
+	result, err := function.Call(interp, args)
+	return result, locate(err, tok)
+}
+
+// placeNamedArgs puts named arguments at the positions of their parameters, after
+// the positional arguments. Parameters in between are left missing for the callee
+// to fill in with their defaults.
+func (interp *Interpreter) placeNamedArgs(function LoxCallable, args []interface{}, names []*token.Token, namedArgs map[*token.Token]interface{}, paren *token.Token) ([]interface{}, error) {
+	namer, ok := function.(parameterNamer)
+	if !ok {
+		return nil, interp.runtimeError(fmt.Sprintf("'%s' doesn't take named arguments", stringify(function)), paren)
+	}
+	for _, name := range names {
+		if name == nil {
+			continue
+		}
+		i, found := namer.ParamIndex(name.Lexeme)
+		if !found {
+			return nil, interp.runtimeError(fmt.Sprintf("'%s' has no parameter called '%s'", stringify(function), name.Lexeme), name)
+		}
+		for len(args) <= i {
+			args = append(args, missingArg)
+		}
+		if args[i] != missingArg {
+			return nil, interp.runtimeError(fmt.Sprintf("argument '%s' is given more than once", name.Lexeme), name)
+		}
+		args[i] = namedArgs[name]
+	}
+	return args, nil
+}
 
+func (interp *Interpreter) checkArity(function LoxCallable, count int, paren *token.Token) error {
+	min, max := function.MinArity(), function.MaxArity()
+	switch {
+	case min == max && count != min:
+		return interp.runtimeError(fmt.Sprintf("expect %d arguments but got %d", min, count), paren)
+	case count < min:
+		return interp.runtimeError(fmt.Sprintf("expect at least %d arguments but got %d", min, count), paren)
+	case max >= 0 && count > max:
+		return interp.runtimeError(fmt.Sprintf("expect at most %d arguments but got %d", max, count), paren)
+	}
+	return nil
 }
 
 func (interp *Interpreter) evaluateGetExpr(expr *expression.Get) (interface{}, error) {
@@ -417,15 +1176,53 @@ func (interp *Interpreter) evaluateGetExpr(expr *expression.Get) (interface{}, e
 	if err != nil {
 		return nil, err
 	}
-	if loxInstance, ok := object.(*LoxInstance); ok {
-		v, err := loxInstance.Get(expr.Name)
+	return interp.getProperty(object, expr.Name)
+}
+
+func (interp *Interpreter) getProperty(object interface{}, name *token.Token) (interface{}, error) {
+	switch v := object.(type) {
+	case *LoxInstance:
+		if getter, ok := v.class.FindGetter(name.Lexeme); ok {
+			val, err := getter.Bind(v).Call(interp, []interface{}{})
+			if retval, ok := val.(*ReturnValue); ok {
+				val = retval.First()
+			}
+			return val, err
+		}
+		val, err := v.Get(name)
 		if err != nil {
-			return nil, err
+			return nil, interp.runtimeError(err.Error(), name)
 		}
-		return v, nil
+		return val, nil
+	case *LoxClass:
+		val, err := v.Get(name)
+		if err != nil {
+			return nil, interp.runtimeError(err.Error(), name)
+		}
+		return val, nil
+	case *LoxList:
+		return v.Get(interp, name)
+	case *LoxMap:
+		return v.Get(interp, name)
+	case *LoxGenerator:
+		return v.Get(interp, name)
+	case *LoxChannel:
+		return v.Get(interp, name)
+	case *LoxModule:
+		val, err := v.Get(name)
+		if err != nil {
+			return nil, interp.runtimeError(err.Error(), name)
+		}
+		return val, nil
+	case *LoxError:
+		val, err := v.Get(name)
+		if err != nil {
+			return nil, interp.runtimeError(err.Error(), name)
+		}
+		return val, nil
 	}
 
-	return nil, errors.New("only class instances have properties that can be accessed")
+	return nil, interp.runtimeError("only class instances have properties that can be accessed", name)
 }
 
 func (interp *Interpreter) evaluateSetExpr(expr *expression.Set) (interface{}, error) {
@@ -433,16 +1230,151 @@ func (interp *Interpreter) evaluateSetExpr(expr *expression.Set) (interface{}, e
 	if err != nil {
 		return nil, err
 	}
-	if loxInstance, ok := object.(*LoxInstance); ok {
-		v, err := interp.evaluate(expr.Value)
+	v, err := interp.evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := interp.setProperty(object, expr.Name, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (interp *Interpreter) setProperty(object interface{}, name *token.Token, val interface{}) error {
+	switch v := object.(type) {
+	case *LoxInstance:
+		if err := v.Set(name, val); err != nil {
+			return interp.runtimeError(err.Error(), name)
+		}
+		return nil
+	case *LoxClass:
+		v.Set(name, val)
+		return nil
+	}
+	return interp.runtimeError("only class instances have properties that can be accessed", name)
+}
+
+func (interp *Interpreter) evaluateMeExpr(expr *expression.Me) (interface{}, error) {
+	return interp.lookUpVariable(expr.Keyword, expr)
+}
+
+func (interp *Interpreter) evaluateBaseExpr(expr *expression.Base) (interface{}, error) {
+	distance, _ := interp.localDepth(expr)
+	superclass := interp.env.GetAt(distance, "base").(*LoxClass)
+	// 'me' is always defined one environment closer than 'base'
+	instance := interp.env.GetAt(distance-1, "me").(*LoxInstance)
+
+	method, found := superclass.FindMethod(expr.Method.Lexeme)
+	if !found {
+		return nil, interp.runtimeError(fmt.Sprintf("undefined property '%s' in superclass '%s'", expr.Method.Lexeme, superclass), expr.Method)
+	}
+	return method.Bind(instance), nil
+}
+
+func (interp *Interpreter) evaluateFunctionExpr(expr *expression.Function) (interface{}, error) {
+	declaration := statement.NewFunctionStmt(nil, expr.Params, expr.Defaults, expr.Rest, expr.Body, expr.IsGenerator)
+	return NewLoxFunction(declaration, interp.env, false), nil
+}
+
+func (interp *Interpreter) evaluateListExpr(expr *expression.List) (interface{}, error) {
+	elements := make([]interface{}, 0, len(expr.Elements))
+	for _, element := range expr.Elements {
+		val, err := interp.evaluate(element)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, val)
+	}
+	return NewLoxList(elements), nil
+}
+
+func (interp *Interpreter) evaluateMapExpr(expr *expression.Map) (interface{}, error) {
+	m := NewLoxMap()
+	for i := range expr.Keys {
+		key, err := interp.evaluate(expr.Keys[i])
+		if err != nil {
+			return nil, err
+		}
+		val, err := interp.evaluate(expr.Values[i])
+		if err != nil {
+			return nil, err
+		}
+		if err := m.SetKey(interp, key, val, expr.Brace); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (interp *Interpreter) evaluateInterpolationExpr(expr *expression.Interpolation) (interface{}, error) {
+	var sb strings.Builder
+	for _, part := range expr.Parts {
+		val, err := interp.evaluate(part)
 		if err != nil {
 			return nil, err
 		}
-		loxInstance.Set(expr.Name, v)
-		return v, nil
+		sb.WriteString(display(val))
+	}
+	return sb.String(), nil
+}
+
+func (interp *Interpreter) evaluateIndexExpr(expr *expression.Index) (interface{}, error) {
+	object, err := interp.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := interp.evaluate(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+	return interp.getIndex(object, index, expr.Bracket)
+}
+
+func (interp *Interpreter) getIndex(object, index interface{}, bracket *token.Token) (interface{}, error) {
+	switch v := object.(type) {
+	case *LoxList:
+		return v.GetAt(interp, index, bracket)
+	case *LoxMap:
+		return v.GetKey(interp, index, bracket)
+	case *LoxInstance:
+		if result, overloaded, err := interp.callSpecial(bracket, v, "__index", index); overloaded || err != nil {
+			return result, err
+		}
+	}
+	return nil, interp.runtimeError(fmt.Sprintf("'%s' can't be indexed", stringify(object)), bracket)
+}
+
+func (interp *Interpreter) evaluateIndexSetExpr(expr *expression.IndexSet) (interface{}, error) {
+	object, err := interp.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := interp.evaluate(expr.Index)
+	if err != nil {
+		return nil, err
 	}
+	val, err := interp.evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := interp.setIndex(object, index, val, expr.Bracket); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
 
-	return nil, errors.New("only class instances have properties that can be accessed")
+func (interp *Interpreter) setIndex(object, index, val interface{}, bracket *token.Token) error {
+	switch v := object.(type) {
+	case *LoxList:
+		return v.SetAt(interp, index, val, bracket)
+	case *LoxMap:
+		return v.SetKey(interp, index, val, bracket)
+	case *LoxInstance:
+		if _, overloaded, err := interp.callSpecial(bracket, v, "__setindex", index, val); overloaded || err != nil {
+			return err
+		}
+	}
+	return interp.runtimeError(fmt.Sprintf("'%s' can't be indexed", stringify(object)), bracket)
 }
 
 func (interp *Interpreter) setEnvironment(env *environment.Environment) {
@@ -450,34 +1382,46 @@ func (interp *Interpreter) setEnvironment(env *environment.Environment) {
 }
 
 func (interp *Interpreter) checkNumberOperand(operator *token.Token, operand interface{}) error {
-	_, ok := operand.(float64)
-	if !ok {
-		return interp.reporter.Report(fmt.Sprintf("operand for binary operator '%s' must be a number", operator.Lexeme), operator)
+	if !isNumber(operand) {
+		return interp.runtimeError(fmt.Sprintf("operand for unary operator '%s' must be a number", operator.Lexeme), operator)
 	}
 	return nil
 }
 
 func (interp *Interpreter) checkNumberOperands(operator *token.Token, left, right interface{}) error {
-	_, lok := left.(float64)
-	if !lok {
-		return interp.reporter.Report(fmt.Sprintf("left operand for binary operator '%s' must be a number", operator.Lexeme), operator)
+	if !isNumber(left) {
+		return interp.runtimeError(fmt.Sprintf("left operand for binary operator '%s' must be a number", operator.Lexeme), operator)
 	}
-	rval, rok := right.(float64)
-	if !rok {
-		return interp.reporter.Report(fmt.Sprintf("right operand for binary operator '%s' must be a number", operator.Lexeme), operator)
+	if !isNumber(right) {
+		return interp.runtimeError(fmt.Sprintf("right operand for binary operator '%s' must be a number", operator.Lexeme), operator)
 	}
 
 	// special case
-	if operator.Type == token.Slash && rval == 0.0 {
-		return interp.reporter.Report("division by zero", operator)
+	if (operator.Type == token.Slash || operator.Type == token.Div || operator.Type == token.Percent) && toFloat(right) == 0.0 {
+		return interp.runtimeError("division by zero", operator)
+
+	}
+	return nil
+}
+
+func (interp *Interpreter) checkIntegerOperands(operator *token.Token, left, right interface{}) error {
+	if _, ok := left.(int64); !ok {
+		return interp.runtimeError(fmt.Sprintf("left operand for binary operator '%s' must be an integer", operator.Lexeme), operator)
+	}
+	rval, ok := right.(int64)
+	if !ok {
+		return interp.runtimeError(fmt.Sprintf("right operand for binary operator '%s' must be an integer", operator.Lexeme), operator)
+	}
 
+	if (operator.Type == token.LessLess || operator.Type == token.GreaterGreater) && rval < 0 {
+		return interp.runtimeError(fmt.Sprintf("shift count for binary operator '%s' must not be negative", operator.Lexeme), operator)
 	}
 	return nil
 }
 
 func (interp *Interpreter) checkEqualityOperands(operator *token.Token, left, right interface{}) error {
-	_, lfloat := left.(float64)
-	_, rfloat := right.(float64)
+	lfloat := isNumber(left)
+	rfloat := isNumber(right)
 
 	_, lstr := left.(string)
 	_, rstr := right.(string)
@@ -486,7 +1430,7 @@ func (interp *Interpreter) checkEqualityOperands(operator *token.Token, left, ri
 	_, rbool := right.(bool)
 
 	if lfloat != rfloat || lstr != rstr || lbool != rbool {
-		return interp.reporter.Report(fmt.Sprintf("left and right operands for binary operator '%s' must be of same type", operator.Lexeme), operator)
+		return interp.runtimeError(fmt.Sprintf("left and right operands for binary operator '%s' must be of same type", operator.Lexeme), operator)
 	}
 
 	return nil
@@ -495,18 +1439,48 @@ func (interp *Interpreter) checkEqualityOperands(operator *token.Token, left, ri
 func (interp *Interpreter) checkStringOperands(operator *token.Token, left, right interface{}) error {
 	_, lok := left.(string)
 	if !lok {
-		return interp.reporter.Report(fmt.Sprintf("left operand for binary operator '%s' must be a string", operator.Lexeme), operator)
+		return interp.runtimeError(fmt.Sprintf("left operand for binary operator '%s' must be a string", operator.Lexeme), operator)
 	}
 	_, rok := right.(string)
 	if !rok {
-		return interp.reporter.Report(fmt.Sprintf("right operand for binary operator '%s' must be a string", operator.Lexeme), operator)
+		return interp.runtimeError(fmt.Sprintf("right operand for binary operator '%s' must be a string", operator.Lexeme), operator)
 	}
 	return nil
 }
 
+// display formats a value the way print shows it. Unlike stringify, strings are
+// not quoted.
+func display(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case float64:
+		return formatFloat(v)
+	}
+	return fmt.Sprintf("%v", val)
+}
+
 func stringify(val interface{}) string {
-	if val == nil {
+	return stringifyIn(val, nil)
+}
+
+// stringifyIn is stringify given the containers being printed further up, so
+// that a list containing itself prints the inner reference as '[...]', and a
+// map as '{...}'.
+func stringifyIn(val interface{}, printing map[interface{}]bool) string {
+	switch v := val.(type) {
+	case *LoxList:
+		return v.format(printing)
+	case *LoxMap:
+		return v.format(printing)
+	case nil:
 		return "null"
+	case fmt.Stringer:
+		return v.String()
+	case float64:
+		return formatFloat(v)
 	}
 	return fmt.Sprintf("%#v", val)
 }
@@ -521,12 +1495,107 @@ func isTruthy(val interface{}) bool {
 	if stringVal, ok := val.(string); ok {
 		return stringVal != ""
 	}
+	if intVal, ok := val.(int64); ok {
+		return intVal != 0
+	}
 	if floatVal, ok := val.(float64); ok {
 		return floatVal != 0.0
 	}
+	if listVal, ok := val.(*LoxList); ok {
+		return listVal.Len() != 0
+	}
+	if mapVal, ok := val.(*LoxMap); ok {
+		return mapVal.Len() != 0
+	}
 	return true
 }
 
+// equal compares two values like '==' does, including the overloaded '__eq'.
+func (interp *Interpreter) equal(tok *token.Token, left, right interface{}) (bool, error) {
+	return interp.equalIn(tok, left, right, nil)
+}
+
+// containerPair is a pair of lists or maps being compared.
+type containerPair struct {
+	left, right interface{}
+}
+
+// equalIn is equal given the pairs of containers that are already being compared
+// further up, so that a list containing itself doesn't recurse forever.
+func (interp *Interpreter) equalIn(tok *token.Token, left, right interface{}, comparing map[containerPair]bool) (bool, error) {
+	if eq, overloaded, err := interp.overloadEqual(tok, left, right); overloaded || err != nil {
+		return eq, err
+	}
+	return interp.equalValuesIn(tok, left, right, comparing)
+}
+
+// equalValues compares two values that don't overload '__eq'. Lists and maps are
+// equal when their elements are, and their elements are compared with equal.
+func (interp *Interpreter) equalValues(tok *token.Token, left, right interface{}) (bool, error) {
+	return interp.equalValuesIn(tok, left, right, nil)
+}
+
+func (interp *Interpreter) equalValuesIn(tok *token.Token, left, right interface{}, comparing map[containerPair]bool) (bool, error) {
+	switch l := left.(type) {
+	case *LoxList:
+		r, ok := right.(*LoxList)
+		if !ok {
+			return false, nil
+		}
+		// a pair compared further up is equal unless some other element differs
+		pair := containerPair{l, r}
+		if comparing[pair] {
+			return true, nil
+		}
+		if comparing == nil {
+			comparing = make(map[containerPair]bool)
+		}
+		comparing[pair] = true
+		defer delete(comparing, pair)
+
+		lelements, relements := l.snapshot(), r.snapshot()
+		if len(lelements) != len(relements) {
+			return false, nil
+		}
+		for i := range lelements {
+			if eq, err := interp.equalIn(tok, lelements[i], relements[i], comparing); !eq || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case *LoxMap:
+		r, ok := right.(*LoxMap)
+		if !ok {
+			return false, nil
+		}
+		pair := containerPair{l, r}
+		if comparing[pair] {
+			return true, nil
+		}
+		if comparing == nil {
+			comparing = make(map[containerPair]bool)
+		}
+		comparing[pair] = true
+		defer delete(comparing, pair)
+
+		lkeys, lvalues := l.snapshot()
+		if len(lkeys) != r.Len() {
+			return false, nil
+		}
+		for i, key := range lkeys {
+			rv, found := r.lookup(key)
+			if !found {
+				return false, nil
+			}
+			if eq, err := interp.equalIn(tok, lvalues[i], rv, comparing); !eq || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return isEqual(left, right), nil
+}
+
 func isEqual(left, right interface{}) bool {
 	if left == nil && right == nil {
 		return true
@@ -535,28 +1604,25 @@ func isEqual(left, right interface{}) bool {
 		return false
 	}
 
-	return compareBools(left, right) || compareFloats(left, right) || compareStrings(left, right)
+	return compareIdentity(left, right) || compareBools(left, right) || compareNumbers(left, right) || compareStrings(left, right)
 }
 
-func compareBools(left, right interface{}) bool {
-	lval, lok := left.(bool)
-	if !lok {
-		return false
-	}
-	rval, rok := right.(bool)
-	if !rok {
-		return false
+// compareIdentity reports whether both values are the same object, such as the
+// same class instance or function.
+func compareIdentity(left, right interface{}) bool {
+	switch left.(type) {
+	case *LoxInstance, *LoxModule, *LoxError, *LoxGenerator, *LoxTask, *LoxChannel, LoxCallable:
+		return left == right
 	}
-
-	return lval == rval
+	return false
 }
 
-func compareFloats(left, right interface{}) bool {
-	lval, lok := left.(float64)
+func compareBools(left, right interface{}) bool {
+	lval, lok := left.(bool)
 	if !lok {
 		return false
 	}
-	rval, rok := right.(float64)
+	rval, rok := right.(bool)
 	if !rok {
 		return false
 	}
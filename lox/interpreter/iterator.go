@@ -0,0 +1,176 @@
+package interpreter
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"golox/lox/token"
+)
+
+// iterator produces the values for the loop variables of a for-in loop, one step
+// at a time. It returns false once there are no more values.
+type iterator func() ([]interface{}, bool, error)
+
+// iterate returns an iterator over the given value for a loop with count loop
+// variables.
+//
+// Lists and strings give their elements, or an index and an element for two
+// variables. Maps give their keys, or a key and a value. Ranges give their
+// numbers, generators the values they yield and channels the values received
+// until they are closed. Class instances take part through an 'iter' method
+// returning anything iterable, such as a generator, or through a 'next' method on
+// the instance or on the object 'iter' returns. The loop calls 'next' until it
+// returns null, and a 'next' that returns two values fills two loop variables.
+func (interp *Interpreter) iterate(iterable interface{}, count int, tok *token.Token) (iterator, error) {
+	switch v := iterable.(type) {
+	case *LoxList:
+		i := 0
+		return func() ([]interface{}, bool, error) {
+			// the length is checked on every step, the list may change in the loop
+			element, found := v.at(i)
+			if !found {
+				return nil, false, nil
+			}
+			values := []interface{}{int64(i), element}
+			i++
+			return values[2-count:], true, nil
+		}, nil
+	case *LoxMap:
+		keys, _ := v.snapshot()
+		i := 0
+		return func() ([]interface{}, bool, error) {
+			for i < len(keys) {
+				key := keys[i]
+				i++
+				// skip keys deleted while looping
+				if val, found := v.lookup(key); found {
+					return []interface{}{key, val}[:count], true, nil
+				}
+			}
+			return nil, false, nil
+		}, nil
+	case string:
+		offset, index := 0, int64(0)
+		return func() ([]interface{}, bool, error) {
+			if offset >= len(v) {
+				return nil, false, nil
+			}
+			_, size := utf8.DecodeRuneInString(v[offset:])
+			values := []interface{}{index, v[offset : offset+size]}
+			offset += size
+			index++
+			return values[2-count:], true, nil
+		}, nil
+	case *LoxRange:
+		if count != 1 {
+			return nil, interp.runtimeError("a range gives one value per step, expect one loop variable", tok)
+		}
+		current, done := v.start, false
+		return func() ([]interface{}, bool, error) {
+			if done || !v.contains(current) {
+				return nil, false, nil
+			}
+			value := current
+			current += v.step
+			// stop rather than wrap around past the largest integer
+			done = (v.step > 0) != (current > value)
+			return []interface{}{value}, true, nil
+		}, nil
+	case *LoxGenerator:
+		if count != 1 {
+			return nil, interp.runtimeError("a generator gives one value per step, expect one loop variable", tok)
+		}
+		return func() ([]interface{}, bool, error) {
+			val, ok, err := v.next(interp)
+			return []interface{}{val}, ok, locate(err, tok)
+		}, nil
+	case *LoxChannel:
+		if count != 1 {
+			return nil, interp.runtimeError("a channel gives one value per step, expect one loop variable", tok)
+		}
+		return func() ([]interface{}, bool, error) {
+			val, ok := v.receive()
+			return []interface{}{val}, ok, nil
+		}, nil
+	case *LoxInstance:
+		return interp.iterateInstance(v, count, tok)
+	}
+	return nil, interp.runtimeError(fmt.Sprintf("'%s' is not iterable", stringify(iterable)), tok)
+}
+
+func (interp *Interpreter) iterateInstance(instance *LoxInstance, count int, tok *token.Token) (iterator, error) {
+	if iter, ok := instance.class.FindMethod("iter"); ok {
+		result, err := interp.callFunction(iter.Bind(instance), []interface{}{}, tok)
+		if err != nil {
+			return nil, err
+		}
+		if retval, ok := result.(*ReturnValue); ok {
+			result = retval.First()
+		}
+		it, ok := result.(*LoxInstance)
+		if !ok {
+			// such as a generator, when 'iter' yields
+			return interp.iterate(result, count, tok)
+		}
+		instance = it
+	}
+
+	next, ok := instance.class.FindMethod("next")
+	if !ok {
+		return nil, interp.runtimeError(fmt.Sprintf("'%s' is not iterable, it has no 'iter' or 'next' method", stringify(instance)), tok)
+	}
+	step := next.Bind(instance)
+	if step.MinArity() != 0 {
+		return nil, interp.runtimeError("an iterator's 'next' method can't take arguments", tok)
+	}
+
+	return func() ([]interface{}, bool, error) {
+		result, err := interp.callFunction(step, []interface{}{}, tok)
+		if err != nil {
+			return nil, false, err
+		}
+		values := []interface{}{result}
+		if retval, ok := result.(*ReturnValue); ok {
+			values = retval.Values
+		}
+		if len(values) == 0 || values[0] == nil {
+			return nil, false, nil
+		}
+		if len(values) < count {
+			return nil, false, interp.runtimeError(fmt.Sprintf("expect %d values from 'next' but got %d", count, len(values)), tok)
+		}
+		return values[:count], true, nil
+	}, nil
+}
+
+// numericSteps returns an iterator over start, start + step, ... up to and including
+// stop, for numeric for loops. It counts in integers when all of its arguments
+// are integers and in floats otherwise.
+func numericSteps(start, stop, step interface{}) iterator {
+	istart, startOk := start.(int64)
+	istop, stopOk := stop.(int64)
+	istep, stepOk := step.(int64)
+	if startOk && stopOk && stepOk {
+		current, done := istart, false
+		return func() ([]interface{}, bool, error) {
+			if done || (istep > 0 && current > istop) || (istep < 0 && current < istop) {
+				return nil, false, nil
+			}
+			value := current
+			current += istep
+			// stop rather than wrap around past the largest integer
+			done = (istep > 0) != (current > value)
+			return []interface{}{value}, true, nil
+		}
+	}
+
+	current, fstop, fstep := toFloat(start), toFloat(stop), toFloat(step)
+	return func() ([]interface{}, bool, error) {
+		if (fstep > 0 && current > fstop) || (fstep < 0 && current < fstop) {
+			return nil, false, nil
+		}
+		value := current
+		current += fstep
+		return []interface{}{value}, true, nil
+	}
+}
@@ -2,19 +2,41 @@ package interpreter
 
 type LoxCallable interface {
 	Call(interp *Interpreter, args []interface{}) (interface{}, error)
-	Arity() int
+	// MinArity and MaxArity bound the number of arguments the callable takes.
+	// MaxArity is -1 when there's no upper bound.
+	MinArity() int
+	MaxArity() int
 	String() string
 }
 
+// parameterNamer is implemented by callables that accept named arguments.
+type parameterNamer interface {
+	// ParamIndex returns the position of the parameter with the given name.
+	ParamIndex(name string) (int, bool)
+}
+
+// missing fills in for the parameters that named arguments skip over.
+type missing struct{}
+
+var missingArg = missing{}
+
 type LoxCallableImpl struct {
-	arity     int
+	minArity  int
+	maxArity  int
 	call      func(interp *Interpreter, args []interface{}) (interface{}, error)
 	stringify func() string
 }
 
 func NewLoxCallable(arity int, callFunc func(interp *Interpreter, args []interface{}) (interface{}, error), strFunc func() string) LoxCallable {
+	return NewLoxVariadicCallable(arity, arity, callFunc, strFunc)
+}
+
+// NewLoxVariadicCallable creates a native function taking between minArity and
+// maxArity arguments, or at least minArity if maxArity is -1.
+func NewLoxVariadicCallable(minArity, maxArity int, callFunc func(interp *Interpreter, args []interface{}) (interface{}, error), strFunc func() string) LoxCallable {
 	return &LoxCallableImpl{
-		arity:     arity,
+		minArity:  minArity,
+		maxArity:  maxArity,
 		call:      callFunc,
 		stringify: strFunc,
 	}
@@ -24,8 +46,12 @@ func (c *LoxCallableImpl) Call(interp *Interpreter, args []interface{}) (interfa
 	return c.call(interp, args)
 }
 
-func (c *LoxCallableImpl) Arity() int {
-	return c.arity
+func (c *LoxCallableImpl) MinArity() int {
+	return c.minArity
+}
+
+func (c *LoxCallableImpl) MaxArity() int {
+	return c.maxArity
 }
 
 func (c *LoxCallableImpl) String() string {
@@ -0,0 +1,103 @@
+package interpreter
+
+import (
+	"fmt"
+	"sync"
+
+	"golox/lox/token"
+)
+
+// LoxChannel passes values between tasks. Sending blocks until another task
+// receives the value, unless the channel has room left in its buffer. Once the
+// channel is closed, receiving gives the values still buffered and then null.
+type LoxChannel struct {
+	values chan interface{}
+	closed chan struct{} // closed by close, values never is
+
+	mu       sync.Mutex
+	isClosed bool
+}
+
+func NewLoxChannel(capacity int) *LoxChannel {
+	return &LoxChannel{
+		values: make(chan interface{}, capacity),
+		closed: make(chan struct{}),
+	}
+}
+
+func (c *LoxChannel) String() string {
+	return fmt.Sprintf("<channel %d/%d>", len(c.values), cap(c.values))
+}
+
+// Get returns the built-in channel method with the given name, bound to the
+// channel.
+func (c *LoxChannel) Get(interp *Interpreter, name *token.Token) (interface{}, error) {
+	var (
+		arity int
+		call  func(interp *Interpreter, args []interface{}) (interface{}, error)
+	)
+
+	switch name.Lexeme {
+	case "send":
+		arity = 1
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			return nil, c.send(args[0])
+		}
+	case "receive":
+		arity = 0
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			// the second value tells a received null apart from a closed channel
+			val, ok := c.receive()
+			return NewReturnValue([]interface{}{val, ok}), nil
+		}
+	case "close":
+		arity = 0
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.isClosed {
+				return nil, nativeError("channel is already closed")
+			}
+			c.isClosed = true
+			close(c.closed)
+			return nil, nil
+		}
+	default:
+		return nil, interp.runtimeError(fmt.Sprintf("channel has no method called '%s'", name.Lexeme), name)
+	}
+
+	return NewLoxCallable(arity, call, func() string {
+		return "<native fn " + name.Lexeme + ">"
+	}), nil
+}
+
+func (c *LoxChannel) send(val interface{}) error {
+	// a select picks at random between ready cases, check for closing first
+	select {
+	case <-c.closed:
+		return nativeError("can't send on a closed channel")
+	default:
+	}
+	select {
+	case c.values <- val:
+		return nil
+	case <-c.closed:
+		return nativeError("can't send on a closed channel")
+	}
+}
+
+// receive returns the next value sent on the channel, or false once the channel
+// is closed and has no values left.
+func (c *LoxChannel) receive() (interface{}, bool) {
+	select {
+	case val := <-c.values:
+		return val, true
+	case <-c.closed:
+		select {
+		case val := <-c.values:
+			return val, true
+		default:
+			return nil, false
+		}
+	}
+}
@@ -1,24 +1,119 @@
 package interpreter
 
+import (
+	"fmt"
+	"sync"
+
+	"golox/lox/token"
+)
+
 type LoxClass struct {
-	name string
+	name          string
+	superclass    *LoxClass
+	methods       map[string]*LoxFunction
+	staticMethods map[string]*LoxFunction
+	getters       map[string]*LoxFunction
+	mu            sync.RWMutex // guards fields, which tasks can assign
+	fields        map[string]interface{}
 }
 
-func NewLoxClass(name string) *LoxClass {
+func NewLoxClass(name string, superclass *LoxClass, methods, staticMethods, getters map[string]*LoxFunction) *LoxClass {
 	return &LoxClass{
-		name: name,
+		name:          name,
+		superclass:    superclass,
+		methods:       methods,
+		staticMethods: staticMethods,
+		getters:       getters,
+		fields:        make(map[string]interface{}),
+	}
+}
+
+func (lc *LoxClass) FindMethod(name string) (*LoxFunction, bool) {
+	if method, found := lc.methods[name]; found {
+		return method, true
+	}
+	if lc.superclass != nil {
+		return lc.superclass.FindMethod(name)
 	}
+	return nil, false
+}
+
+func (lc *LoxClass) FindGetter(name string) (*LoxFunction, bool) {
+	if getter, found := lc.getters[name]; found {
+		return getter, true
+	}
+	if lc.superclass != nil {
+		return lc.superclass.FindGetter(name)
+	}
+	return nil, false
+}
+
+// FindField looks up a class level variable, which subclasses inherit.
+func (lc *LoxClass) FindField(name string) (interface{}, bool) {
+	lc.mu.RLock()
+	val, found := lc.fields[name]
+	lc.mu.RUnlock()
+	if found {
+		return val, true
+	}
+	if lc.superclass != nil {
+		return lc.superclass.FindField(name)
+	}
+	return nil, false
+}
+
+// Get returns a class level variable or a static method.
+func (lc *LoxClass) Get(name *token.Token) (interface{}, error) {
+	if val, found := lc.FindField(name.Lexeme); found {
+		return val, nil
+	}
+	for class := lc; class != nil; class = class.superclass {
+		if method, found := class.staticMethods[name.Lexeme]; found {
+			return method, nil
+		}
+	}
+	return nil, fmt.Errorf("class '%s' has no static member called '%s'", lc.name, name.Lexeme)
+}
+
+// Set assigns a class level variable. A subclass assigning an inherited variable
+// gets a variable of its own.
+func (lc *LoxClass) Set(name *token.Token, val interface{}) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.fields[name.Lexeme] = val
 }
 
 func (lc *LoxClass) Call(interp *Interpreter, args []interface{}) (interface{}, error) {
 	instance := NewLoxInstance(lc)
+	if initializer, found := lc.FindMethod("init"); found {
+		if _, err := initializer.Bind(instance).Call(interp, args); err != nil {
+			return nil, err
+		}
+	}
 	return instance, nil
 }
 
-func (lx *LoxClass) Arity() int {
+func (lc *LoxClass) MinArity() int {
+	if initializer, found := lc.FindMethod("init"); found {
+		return initializer.MinArity()
+	}
+	return 0
+}
+
+func (lc *LoxClass) MaxArity() int {
+	if initializer, found := lc.FindMethod("init"); found {
+		return initializer.MaxArity()
+	}
 	return 0
 }
 
+func (lc *LoxClass) ParamIndex(name string) (int, bool) {
+	if initializer, found := lc.FindMethod("init"); found {
+		return initializer.ParamIndex(name)
+	}
+	return 0, false
+}
+
 func (lc *LoxClass) String() string {
 	return lc.name
 }
@@ -1,41 +1,112 @@
 package interpreter
 
 import (
+	"errors"
+	"fmt"
 	"golox/lox/environment"
-	"golox/lox/expression"
 	"golox/lox/statement"
 )
 
 type LoxFunction struct {
-	declaration *statement.FunctionStmt
-	closure     *environment.Environment
+	declaration   *statement.FunctionStmt
+	closure       *environment.Environment
+	isInitializer bool
 }
 
-func NewLoxFunction(decl *statement.FunctionStmt, closure *environment.Environment) *LoxFunction {
+func NewLoxFunction(decl *statement.FunctionStmt, closure *environment.Environment, isInitializer bool) *LoxFunction {
 	return &LoxFunction{
-		declaration: decl,
-		closure:     closure,
+		declaration:   decl,
+		closure:       closure,
+		isInitializer: isInitializer,
 	}
 }
 
+// Bind returns a copy of the method whose closure has 'me' set to the instance.
+func (f *LoxFunction) Bind(instance *LoxInstance) *LoxFunction {
+	env := environment.NewEnvironment(f.closure)
+	env.Define("me", instance)
+	return NewLoxFunction(f.declaration, env, f.isInitializer)
+}
+
 func (f *LoxFunction) Call(interp *Interpreter, args []interface{}) (interface{}, error) {
 	env := environment.NewEnvironment(f.closure)
-	// Interpreter.evaluateCallExpr() already checks if the number of arguments match
-	for i := 0; i < len(f.declaration.Params); i++ {
-		env.Define(f.declaration.Params[i].Lexeme, args[i])
+	// Interpreter.call() already checks the number of arguments, parameters without
+	// an argument have a default value or were skipped over by named arguments
+	for i, param := range f.declaration.Params {
+		if i < len(args) && args[i] != missingArg {
+			env.Define(param.Lexeme, args[i])
+			continue
+		}
+		if f.declaration.Defaults[i] == nil {
+			return nil, nativeError(fmt.Sprintf("missing argument for parameter '%s'", param.Lexeme))
+		}
+		// defaults are evaluated on every call, in the scope of the function
+		val, err := interp.evaluateIn(f.declaration.Defaults[i], env)
+		if err != nil {
+			return nil, err
+		}
+		env.Define(param.Lexeme, val)
+	}
+	if f.declaration.Rest != nil {
+		rest := make([]interface{}, 0)
+		if len(args) > len(f.declaration.Params) {
+			rest = append(rest, args[len(f.declaration.Params):]...)
+		}
+		env.Define(f.declaration.Rest.Lexeme, NewLoxList(rest))
+	}
+	// calling a generator function only binds its arguments, the body runs later
+	if f.declaration.IsGenerator {
+		return NewLoxGenerator(f, env), nil
 	}
-	retval, err := interp.executeBlock(f.declaration.Body, env)
-	if err != nil {
+	err := interp.executeBlock(f.declaration.Body, env)
+
+	var returned *ReturnValue
+	if err != nil && !errors.As(err, &returned) {
 		return nil, err
 	}
-	if _, isNull := retval.(expression.NullExpr); isNull {
+	// initializers always return the instance, even on an empty 'return'
+	if f.isInitializer {
+		return f.closure.GetAt(0, "me"), nil
+	}
+	if returned == nil {
 		return nil, nil
 	}
-	return retval, nil
+	// a function returning multiple values hands the whole ReturnValue to the caller
+	if len(returned.Values) > 1 {
+		return returned, nil
+	}
+	return returned.First(), nil
+}
+
+func (f *LoxFunction) MinArity() int {
+	arity := 0
+	for _, defaultValue := range f.declaration.Defaults {
+		if defaultValue == nil {
+			arity++
+		}
+	}
+	return arity
 }
-func (f *LoxFunction) Arity() int {
+
+func (f *LoxFunction) MaxArity() int {
+	if f.declaration.Rest != nil {
+		return -1
+	}
 	return len(f.declaration.Params)
 }
+
+func (f *LoxFunction) ParamIndex(name string) (int, bool) {
+	for i, param := range f.declaration.Params {
+		if param.Lexeme == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
 func (f *LoxFunction) String() string {
+	if f.declaration.Name == nil {
+		return "<fn anonymous>"
+	}
 	return "<fn " + f.declaration.Name.Lexeme + ">"
 }
@@ -0,0 +1,269 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"golox/lox/environment"
+	"golox/lox/token"
+)
+
+// LoxGenerator is returned by calling a function that contains a yield statement.
+// The body of the function doesn't run until the first call to 'next', which
+// runs it up to the first yield and returns the yielded value. Every call after
+// that resumes the body where it left off.
+//
+// The body runs on a goroutine of its own, which takes turns with the caller:
+// only one of them runs at a time, so they never touch the interpreter state
+// together. A generator that is dropped before it finishes has its goroutine
+// stopped once the generator is garbage collected, without running any more of
+// its body. Closing a generator explicitly runs its finally blocks instead.
+//
+// A suspended body keeps its closure reachable, so a generator held in a global
+// variable, or in the scope its generator function was defined in, is never
+// collected. The interpreter stops those once the script has finished.
+type LoxGenerator struct {
+	// the goroutine only ever references the coroutine, never the generator, so
+	// that an abandoned generator can still be garbage collected
+	co *coroutine
+}
+
+type coroutine struct {
+	function *LoxFunction
+	env      *environment.Environment // holds the arguments of the call
+
+	resume      chan bool // true resumes the body, false closes the generator
+	steps       chan generatorStep
+	abandon     chan struct{} // closed once nobody can resume the generator
+	abandonOnce sync.Once
+	exited      chan struct{} // closed once the goroutine has returned
+
+	mu       sync.Mutex // tasks can share generators, but only one can resume it
+	started  bool
+	running  bool
+	finished bool
+	closing  bool // only used by the goroutine
+}
+
+// generatorStep is what the body of a generator hands back to its caller, either
+// a yielded value or the end of the body.
+type generatorStep struct {
+	value interface{}
+	err   error
+	done  bool
+}
+
+func NewLoxGenerator(function *LoxFunction, env *environment.Environment) *LoxGenerator {
+	g := &LoxGenerator{
+		co: &coroutine{
+			function: function,
+			env:      env,
+			resume:   make(chan bool),
+			steps:    make(chan generatorStep),
+			abandon:  make(chan struct{}),
+			exited:   make(chan struct{}),
+		},
+	}
+	runtime.SetFinalizer(g, func(g *LoxGenerator) {
+		g.co.stop()
+	})
+	return g
+}
+
+func (g *LoxGenerator) String() string {
+	name := g.co.function.declaration.Name
+	if name == nil {
+		return "<generator anonymous>"
+	}
+	return "<generator " + name.Lexeme + ">"
+}
+
+// Get returns the built-in generator method with the given name, bound to the
+// generator.
+func (g *LoxGenerator) Get(interp *Interpreter, name *token.Token) (interface{}, error) {
+	var call func(interp *Interpreter, args []interface{}) (interface{}, error)
+
+	switch name.Lexeme {
+	case "next":
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			val, _, err := g.next(interp)
+			return val, err
+		}
+	case "done":
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			g.co.mu.Lock()
+			defer g.co.mu.Unlock()
+			return g.co.finished, nil
+		}
+	case "close":
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			return nil, g.close()
+		}
+	default:
+		return nil, interp.runtimeError(fmt.Sprintf("generator has no method called '%s'", name.Lexeme), name)
+	}
+
+	return NewLoxCallable(0, call, func() string {
+		return "<native fn " + name.Lexeme + ">"
+	}), nil
+}
+
+// next runs the body of the generator up to its next yield. It returns false
+// once the body has finished, and the error the body raised, if any.
+func (g *LoxGenerator) next(interp *Interpreter) (interface{}, bool, error) {
+	co := g.co
+	co.mu.Lock()
+	if co.finished {
+		co.mu.Unlock()
+		return nil, false, nil
+	}
+	if co.running {
+		co.mu.Unlock()
+		return nil, false, nativeError("generator is already running")
+	}
+	co.running = true
+	started := co.started
+	co.started = true
+	co.mu.Unlock()
+
+	if started {
+		co.resume <- true
+	} else {
+		interp.generators.add(co)
+		go co.run(interp)
+	}
+	step := <-co.steps
+
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	co.running = false
+	if step.done {
+		co.finished = true
+		return nil, false, step.err
+	}
+	return step.value, true, nil
+}
+
+// close finishes the generator. A suspended body is unwound from its yield, which
+// runs the finally blocks around it.
+func (g *LoxGenerator) close() error {
+	co := g.co
+	co.mu.Lock()
+	if co.running {
+		co.mu.Unlock()
+		return nativeError("can't close a running generator")
+	}
+	if co.finished || !co.started {
+		co.finished = true
+		co.mu.Unlock()
+		return nil
+	}
+	co.finished = true
+	co.running = true
+	co.mu.Unlock()
+
+	co.resume <- false
+	step := <-co.steps
+
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	co.running = false
+	return step.err
+}
+
+func (co *coroutine) run(interp *Interpreter) {
+	defer close(co.exited)
+	defer interp.generators.remove(co)
+
+	// the body runs on an interpreter of its own, the caller keeps using interp.
+	// Nothing of the caller's environment is kept, or the goroutine could keep
+	// the generator reachable through the variable the caller holds it in.
+	fork := interp.fork()
+	fork.env = co.env
+	fork.generator = co
+	err := fork.executeBlock(co.function.declaration.Body, co.env)
+
+	var returned *ReturnValue
+	if errors.As(err, &returned) || errors.Is(err, errGeneratorClosed) {
+		err = nil
+	}
+	co.steps <- generatorStep{err: err, done: true}
+}
+
+// yield hands the value to the caller and suspends the body until the caller
+// asks for the next one.
+func (co *coroutine) yield(val interface{}) error {
+	// a finally block that yields while the generator is being closed
+	if co.closing {
+		return errGeneratorClosed
+	}
+	co.steps <- generatorStep{value: val}
+	select {
+	case resumed := <-co.resume:
+		if !resumed {
+			co.closing = true
+			return errGeneratorClosed
+		}
+		return nil
+	case <-co.abandon:
+		// nobody will ever ask for the rest of the body
+		runtime.Goexit()
+		return nil
+	}
+}
+
+// stop makes a suspended body return without running any more of it.
+func (co *coroutine) stop() {
+	co.abandonOnce.Do(func() {
+		close(co.abandon)
+	})
+}
+
+// generatorSet holds the generators whose bodies have started but not finished,
+// shared by the interpreters of all tasks.
+type generatorSet struct {
+	mu   sync.Mutex
+	live map[*coroutine]struct{}
+}
+
+func newGeneratorSet() *generatorSet {
+	return &generatorSet{
+		live: make(map[*coroutine]struct{}),
+	}
+}
+
+func (s *generatorSet) add(co *coroutine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.live[co] = struct{}{}
+}
+
+func (s *generatorSet) remove(co *coroutine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.live, co)
+}
+
+// stopAll stops the generators that are still suspended and waits for their
+// goroutines to return.
+func (s *generatorSet) stopAll() {
+	s.mu.Lock()
+	live := make([]*coroutine, 0, len(s.live))
+	for co := range s.live {
+		live = append(live, co)
+	}
+	s.mu.Unlock()
+
+	for _, co := range live {
+		co.mu.Lock()
+		suspended := !co.running
+		co.mu.Unlock()
+		co.stop()
+		// a body being run by a task that is still going stops at its next yield
+		if suspended {
+			<-co.exited
+		}
+	}
+}
@@ -3,10 +3,12 @@ package interpreter
 import (
 	"fmt"
 	"golox/lox/token"
+	"sync"
 )
 
 type LoxInstance struct {
 	class  *LoxClass
+	mu     sync.RWMutex // tasks can share instances
 	fields map[string]interface{}
 }
 
@@ -21,13 +23,45 @@ func (li *LoxInstance) String() string {
 	return fmt.Sprintf("%s instance", li.class)
 }
 
+// Get looks a property up in the instance's fields, then its class's methods,
+// and finally the class level variables. Getters are called by the interpreter,
+// an instance can't have a field with the name of a getter.
 func (li *LoxInstance) Get(name *token.Token) (interface{}, error) {
-	if v, ok := li.fields[name.Lexeme]; ok {
+	if v, ok := li.field(name.Lexeme); ok {
+		return v, nil
+	}
+	if method, ok := li.class.FindMethod(name.Lexeme); ok {
+		return method.Bind(li), nil
+	}
+	if v, ok := li.class.FindField(name.Lexeme); ok {
 		return v, nil
 	}
 	return nil, fmt.Errorf("class '%s' has no property called '%s'", li.class.String(), name.Lexeme)
 }
 
-func (li *LoxInstance) Set(name *token.Token, val interface{}) {
+func (li *LoxInstance) Set(name *token.Token, val interface{}) error {
+	if _, ok := li.class.FindGetter(name.Lexeme); ok {
+		return fmt.Errorf("can't assign to '%s', it's a getter", name.Lexeme)
+	}
+	li.mu.Lock()
+	defer li.mu.Unlock()
 	li.fields[name.Lexeme] = val
+	return nil
+}
+
+func (li *LoxInstance) field(name string) (interface{}, bool) {
+	li.mu.RLock()
+	defer li.mu.RUnlock()
+	v, ok := li.fields[name]
+	return v, ok
+}
+
+// IsInstanceOf reports whether the instance's class is class or inherits from it.
+func (li *LoxInstance) IsInstanceOf(class *LoxClass) bool {
+	for c := li.class; c != nil; c = c.superclass {
+		if c == class {
+			return true
+		}
+	}
+	return false
 }
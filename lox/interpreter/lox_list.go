@@ -0,0 +1,214 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"golox/lox/token"
+)
+
+// LoxList is a growable array. Tasks can share lists, so the elements are only
+// touched with the lock held.
+type LoxList struct {
+	mu       sync.Mutex
+	elements []interface{}
+}
+
+func NewLoxList(elements []interface{}) *LoxList {
+	return &LoxList{
+		elements: elements,
+	}
+}
+
+func (l *LoxList) String() string {
+	return l.format(nil)
+}
+
+// format prints the list given the containers being printed further up.
+func (l *LoxList) format(printing map[interface{}]bool) string {
+	if printing[l] {
+		return "[...]"
+	}
+	if printing == nil {
+		printing = make(map[interface{}]bool)
+	}
+	printing[l] = true
+	defer delete(printing, l)
+
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i, element := range l.snapshot() {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(stringifyIn(element, printing))
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+func (l *LoxList) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.elements)
+}
+
+// snapshot returns a copy of the elements, to be used without holding the lock.
+func (l *LoxList) snapshot() []interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	elements := make([]interface{}, len(l.elements))
+	copy(elements, l.elements)
+	return elements
+}
+
+// at returns the element at position i, or false past the end of the list.
+func (l *LoxList) at(i int) (interface{}, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i >= len(l.elements) {
+		return nil, false
+	}
+	return l.elements[i], true
+}
+
+func (l *LoxList) GetAt(interp *Interpreter, index interface{}, tok *token.Token) (interface{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i, err := l.index(interp, index, tok, false)
+	if err != nil {
+		return nil, err
+	}
+	return l.elements[i], nil
+}
+
+func (l *LoxList) SetAt(interp *Interpreter, index interface{}, val interface{}, tok *token.Token) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i, err := l.index(interp, index, tok, false)
+	if err != nil {
+		return err
+	}
+	l.elements[i] = val
+	return nil
+}
+
+// Get returns the built-in list method with the given name, bound to the list.
+func (l *LoxList) Get(interp *Interpreter, name *token.Token) (interface{}, error) {
+	var (
+		arity int
+		call  func(interp *Interpreter, args []interface{}) (interface{}, error)
+	)
+
+	switch name.Lexeme {
+	case "len":
+		arity = 0
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			return int64(len(l.elements)), nil
+		}
+	case "push":
+		arity = 1
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.elements = append(l.elements, args[0])
+			return nil, nil
+		}
+	case "pop":
+		arity = 0
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if len(l.elements) == 0 {
+				return nil, interp.runtimeError("can't pop from an empty list", name)
+			}
+			last := l.elements[len(l.elements)-1]
+			l.elements = l.elements[:len(l.elements)-1]
+			return last, nil
+		}
+	case "insert":
+		arity = 2
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			i, err := l.index(interp, args[0], name, true)
+			if err != nil {
+				return nil, err
+			}
+			l.elements = append(l.elements, nil)
+			copy(l.elements[i+1:], l.elements[i:])
+			l.elements[i] = args[1]
+			return nil, nil
+		}
+	case "remove":
+		arity = 1
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			i, err := l.index(interp, args[0], name, false)
+			if err != nil {
+				return nil, err
+			}
+			removed := l.elements[i]
+			l.elements = append(l.elements[:i], l.elements[i+1:]...)
+			return removed, nil
+		}
+	case "slice":
+		arity = 2
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			from, err := l.index(interp, args[0], name, true)
+			if err != nil {
+				return nil, err
+			}
+			to, err := l.index(interp, args[1], name, true)
+			if err != nil {
+				return nil, err
+			}
+			if from > to {
+				return nil, interp.runtimeError(fmt.Sprintf("slice start %d is greater than its end %d", from, to), name)
+			}
+			elements := make([]interface{}, to-from)
+			copy(elements, l.elements[from:to])
+			return NewLoxList(elements), nil
+		}
+	default:
+		return nil, interp.runtimeError(fmt.Sprintf("list has no method called '%s'", name.Lexeme), name)
+	}
+
+	return NewLoxCallable(arity, call, func() string {
+		return "<native fn " + name.Lexeme + ">"
+	}), nil
+}
+
+// index converts a Lox number to a position in the list. Floats are accepted as
+// long as they hold a whole number. With allowEnd set the position right after
+// the last element is valid too, as when inserting or slicing. The caller holds
+// the lock.
+func (l *LoxList) index(interp *Interpreter, val interface{}, tok *token.Token, allowEnd bool) (int, error) {
+	var num int64
+	switch v := val.(type) {
+	case int64:
+		num = v
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, interp.runtimeError(fmt.Sprintf("list index must be a whole number, got '%s'", stringify(val)), tok)
+		}
+		num = int64(v)
+	default:
+		return 0, interp.runtimeError(fmt.Sprintf("list index must be a whole number, got '%s'", stringify(val)), tok)
+	}
+	limit := int64(len(l.elements))
+	if allowEnd {
+		limit++
+	}
+	if num < 0 || num >= limit {
+		return 0, interp.runtimeError(fmt.Sprintf("list index %d out of bounds for list of length %d", num, len(l.elements)), tok)
+	}
+	return int(num), nil
+}
@@ -0,0 +1,189 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"golox/lox/token"
+)
+
+// LoxMap is an associative array that keeps its keys in insertion order. Keys are
+// restricted to strings, numbers, booleans and null. Floats holding a whole number
+// are stored as integers, so m[1] and m[1.0] name the same entry. Reading a missing
+// key gives null; 'has' tells a missing key apart from one holding null. Tasks
+// can share maps, so the entries are only touched with the lock held.
+type LoxMap struct {
+	mu     sync.Mutex
+	keys   []interface{}
+	values map[interface{}]interface{}
+}
+
+func NewLoxMap() *LoxMap {
+	return &LoxMap{
+		keys:   make([]interface{}, 0),
+		values: make(map[interface{}]interface{}),
+	}
+}
+
+func (m *LoxMap) String() string {
+	return m.format(nil)
+}
+
+// format prints the map given the containers being printed further up.
+func (m *LoxMap) format(printing map[interface{}]bool) string {
+	if printing[m] {
+		return "{...}"
+	}
+	if printing == nil {
+		printing = make(map[interface{}]bool)
+	}
+	printing[m] = true
+	defer delete(printing, m)
+
+	var sb strings.Builder
+	sb.WriteString("{")
+	keys, values := m.snapshot()
+	for i, key := range keys {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(stringifyIn(key, printing))
+		sb.WriteString(": ")
+		sb.WriteString(stringifyIn(values[i], printing))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func (m *LoxMap) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.keys)
+}
+
+// snapshot returns copies of the keys and their values in order, to be used
+// without holding the lock.
+func (m *LoxMap) snapshot() ([]interface{}, []interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]interface{}, len(m.keys))
+	copy(keys, m.keys)
+	values := make([]interface{}, len(m.keys))
+	for i, key := range m.keys {
+		values[i] = m.values[key]
+	}
+	return keys, values
+}
+
+// lookup returns the value stored under a key that is already in stored form.
+func (m *LoxMap) lookup(key interface{}) (interface{}, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, found := m.values[key]
+	return val, found
+}
+
+func (m *LoxMap) GetKey(interp *Interpreter, key interface{}, tok *token.Token) (interface{}, error) {
+	key, err := m.checkKey(interp, key, tok)
+	if err != nil {
+		return nil, err
+	}
+	val, _ := m.lookup(key)
+	return val, nil
+}
+
+func (m *LoxMap) SetKey(interp *Interpreter, key interface{}, val interface{}, tok *token.Token) error {
+	key, err := m.checkKey(interp, key, tok)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, found := m.values[key]; !found {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = val
+	return nil
+}
+
+// Get returns the built-in map method with the given name, bound to the map.
+func (m *LoxMap) Get(interp *Interpreter, name *token.Token) (interface{}, error) {
+	var (
+		arity int
+		call  func(interp *Interpreter, args []interface{}) (interface{}, error)
+	)
+
+	switch name.Lexeme {
+	case "len":
+		arity = 0
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			return int64(m.Len()), nil
+		}
+	case "keys":
+		arity = 0
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			keys, _ := m.snapshot()
+			return NewLoxList(keys), nil
+		}
+	case "values":
+		arity = 0
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			_, values := m.snapshot()
+			return NewLoxList(values), nil
+		}
+	case "has":
+		arity = 1
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			key, err := m.checkKey(interp, args[0], name)
+			if err != nil {
+				return nil, err
+			}
+			_, found := m.lookup(key)
+			return found, nil
+		}
+	case "delete":
+		arity = 1
+		call = func(interp *Interpreter, args []interface{}) (interface{}, error) {
+			key, err := m.checkKey(interp, args[0], name)
+			if err != nil {
+				return nil, err
+			}
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			val, found := m.values[key]
+			if !found {
+				return nil, nil
+			}
+			delete(m.values, key)
+			for i, k := range m.keys {
+				if k == key {
+					m.keys = append(m.keys[:i], m.keys[i+1:]...)
+					break
+				}
+			}
+			return val, nil
+		}
+	default:
+		return nil, interp.runtimeError(fmt.Sprintf("map has no method called '%s'", name.Lexeme), name)
+	}
+
+	return NewLoxCallable(arity, call, func() string {
+		return "<native fn " + name.Lexeme + ">"
+	}), nil
+}
+
+// checkKey validates a key and returns the form it is stored under.
+func (m *LoxMap) checkKey(interp *Interpreter, key interface{}, tok *token.Token) (interface{}, error) {
+	switch k := key.(type) {
+	case float64:
+		if k == math.Trunc(k) && k >= math.MinInt64 && k < math.MaxInt64 {
+			return int64(k), nil
+		}
+		return k, nil
+	case nil, string, int64, bool:
+		return key, nil
+	}
+	return nil, interp.runtimeError(fmt.Sprintf("map key must be a string, number, boolean or null, got '%s'", stringify(key)), tok)
+}
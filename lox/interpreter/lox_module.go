@@ -0,0 +1,40 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+
+	"golox/lox/environment"
+	"golox/lox/statement"
+	"golox/lox/token"
+)
+
+// ModuleLoader reads, parses and resolves the module at the given path.
+type ModuleLoader func(path string) ([]statement.Stmt, error)
+
+// LoxModule is the namespace object of an imported module. Every global of the
+// module is exported, except the ones whose names start with an underscore.
+type LoxModule struct {
+	name string
+	env  *environment.Environment
+}
+
+func NewLoxModule(name string, env *environment.Environment) *LoxModule {
+	return &LoxModule{
+		name: name,
+		env:  env,
+	}
+}
+
+func (m *LoxModule) String() string {
+	return "<module " + m.name + ">"
+}
+
+func (m *LoxModule) Get(name *token.Token) (interface{}, error) {
+	if !strings.HasPrefix(name.Lexeme, "_") {
+		if v, found := m.env.Lookup(name.Lexeme); found {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("module '%s' doesn't export '%s'", m.name, name.Lexeme)
+}
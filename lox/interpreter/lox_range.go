@@ -0,0 +1,28 @@
+package interpreter
+
+import "fmt"
+
+// LoxRange is the lazy sequence of integers returned by the 'range' native,
+// from start up to but not including stop.
+type LoxRange struct {
+	start, stop, step int64
+}
+
+func NewLoxRange(start, stop, step int64) *LoxRange {
+	return &LoxRange{
+		start: start,
+		stop:  stop,
+		step:  step,
+	}
+}
+
+func (r *LoxRange) String() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.start, r.stop, r.step)
+}
+
+func (r *LoxRange) contains(n int64) bool {
+	if r.step > 0 {
+		return n < r.stop
+	}
+	return n > r.stop
+}
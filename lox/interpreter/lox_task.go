@@ -0,0 +1,78 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"golox/lox/expression"
+)
+
+// LoxTask is a function call running on a goroutine of its own, started by a
+// spawn expression. Waiting for a task gives the values the function returned, or
+// raises the error it failed with. Nothing waits for the tasks that are still
+// running when the script ends, so a script waits for the ones it needs.
+//
+// Reading or writing a variable is safe from any task, but a compound assignment
+// like 'n += 1' reads and writes it in two steps that other tasks can get in
+// between of. Tasks that update a shared variable guard it, with a channel for
+// instance.
+type LoxTask struct {
+	function LoxCallable
+	done     chan struct{} // closed once the call has returned
+	result   interface{}
+	err      error
+}
+
+func NewLoxTask(function LoxCallable) *LoxTask {
+	return &LoxTask{
+		function: function,
+		done:     make(chan struct{}),
+	}
+}
+
+func (t *LoxTask) String() string {
+	return "<task " + t.function.String() + ">"
+}
+
+func (interp *Interpreter) evaluateSpawnExpr(expr *expression.Spawn) (interface{}, error) {
+	// the function and its arguments are evaluated by the spawning task
+	function, args, err := interp.callee(expr.Call)
+	if err != nil {
+		return nil, err
+	}
+	if err := interp.checkArity(function, len(args), expr.Call.Paren); err != nil {
+		return nil, err
+	}
+
+	task := NewLoxTask(function)
+	go func(interp *Interpreter) {
+		defer close(task.done)
+		task.result, task.err = interp.callFunction(function, args, expr.Call.Paren)
+	}(interp.fork())
+	return task, nil
+}
+
+func (interp *Interpreter) evaluateWaitExpr(expr *expression.Wait) (interface{}, error) {
+	result, err := interp.wait(expr)
+	if err != nil {
+		return nil, err
+	}
+	// only the first of multiple return values is used in a single value context
+	if retval, ok := result.(*ReturnValue); ok {
+		return retval.First(), nil
+	}
+	return result, nil
+}
+
+// wait blocks until the task has finished and returns its result.
+func (interp *Interpreter) wait(expr *expression.Wait) (interface{}, error) {
+	val, err := interp.evaluate(expr.Task)
+	if err != nil {
+		return nil, err
+	}
+	task, ok := val.(*LoxTask)
+	if !ok {
+		return nil, interp.runtimeError(fmt.Sprintf("can only wait for a task, got '%s'", stringify(val)), expr.Keyword)
+	}
+	<-task.done
+	return task.result, task.err
+}
@@ -0,0 +1,190 @@
+package interpreter
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"golox/lox/token"
+)
+
+// Lox has two kinds of numbers: integers (int64), which whole-number literals
+// evaluate to, and floats (float64). Arithmetic on two integers gives an integer
+// and wraps around on overflow the same way Go's int64 does, so
+// 9223372036854775807 + 1 is -9223372036854775808. As soon as one operand is a
+// float the other one is converted and the result is a float.
+//
+// '/' always divides as floats, so 7 / 2 is 3.5. 'div' is floor division: it
+// rounds towards negative infinity and gives an integer for integer operands
+// (7 div 2 is 3, -7 div 2 is -4). '%' is the matching remainder, it takes the
+// sign of the divisor so that a == (a div b) * b + a % b. '**' gives an integer
+// for an integer raised to a non-negative integer and a float otherwise.
+// Bitwise operators only accept integers.
+
+func isNumber(val interface{}) bool {
+	switch val.(type) {
+	case int64, float64:
+		return true
+	}
+	return false
+}
+
+func toFloat(val interface{}) float64 {
+	switch v := val.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return math.NaN()
+}
+
+// arithmetic applies '+', '-', '*', '/', 'div', '%' or '**' to two numbers.
+func arithmetic(operator *token.Token, left, right interface{}) interface{} {
+	lint, lok := left.(int64)
+	rint, rok := right.(int64)
+	if lok && rok {
+		switch operator.Type {
+		case token.Plus:
+			return lint + rint
+		case token.Minus:
+			return lint - rint
+		case token.Star:
+			return lint * rint
+		case token.Div:
+			return floorDiv(lint, rint)
+		case token.Percent:
+			return floorMod(lint, rint)
+		case token.StarStar:
+			if rint >= 0 {
+				return intPow(lint, rint)
+			}
+		}
+	}
+
+	lval, rval := toFloat(left), toFloat(right)
+	switch operator.Type {
+	case token.Plus:
+		return lval + rval
+	case token.Minus:
+		return lval - rval
+	case token.Star:
+		return lval * rval
+	case token.Slash:
+		return lval / rval
+	case token.Div:
+		return math.Floor(lval / rval)
+	case token.Percent:
+		mod := math.Mod(lval, rval)
+		if mod != 0 && (mod < 0) != (rval < 0) {
+			mod += rval
+		}
+		return mod
+	case token.StarStar:
+		return math.Pow(lval, rval)
+	}
+	return nil
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+func floorMod(a, b int64) int64 {
+	mod := a % b
+	if mod != 0 && (mod < 0) != (b < 0) {
+		mod += b
+	}
+	return mod
+}
+
+// intPow raises base to a non-negative exponent by repeated squaring, wrapping
+// around on overflow like the other integer operators.
+func intPow(base, exp int64) int64 {
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+	return result
+}
+
+// bitwise applies '&', '|', '^', '<<' or '>>' to two integers. Shifts by 64 or
+// more bits give 0 (or -1 when shifting a negative number right).
+func bitwise(operator *token.Token, left, right int64) int64 {
+	switch operator.Type {
+	case token.Ampersand:
+		return left & right
+	case token.Pipe:
+		return left | right
+	case token.Caret:
+		return left ^ right
+	case token.LessLess:
+		return left << uint64(right)
+	case token.GreaterGreater:
+		return left >> uint64(right)
+	}
+	return 0
+}
+
+// orderNumbers applies '<', '<=', '>' or '>=' to two numbers.
+func orderNumbers(operator *token.Token, left, right interface{}) bool {
+	lint, lok := left.(int64)
+	rint, rok := right.(int64)
+	if lok && rok {
+		switch operator.Type {
+		case token.Less:
+			return lint < rint
+		case token.LessEqual:
+			return lint <= rint
+		case token.Greater:
+			return lint > rint
+		case token.GreaterEqual:
+			return lint >= rint
+		}
+	}
+
+	lval, rval := toFloat(left), toFloat(right)
+	switch operator.Type {
+	case token.Less:
+		return lval < rval
+	case token.LessEqual:
+		return lval <= rval
+	case token.Greater:
+		return lval > rval
+	case token.GreaterEqual:
+		return lval >= rval
+	}
+	return false
+}
+
+// compareNumbers reports whether both values are numbers with the same value,
+// so 1 == 1.0 holds.
+func compareNumbers(left, right interface{}) bool {
+	if !isNumber(left) || !isNumber(right) {
+		return false
+	}
+	lint, lok := left.(int64)
+	rint, rok := right.(int64)
+	if lok && rok {
+		return lint == rint
+	}
+	return toFloat(left) == toFloat(right)
+}
+
+// formatFloat formats a float so that it can't be mistaken for an integer: whole
+// floats keep a trailing ".0".
+func formatFloat(val float64) string {
+	str := strconv.FormatFloat(val, 'g', -1, 64)
+	if strings.ContainsAny(str, ".eIN") {
+		return str
+	}
+	return str + ".0"
+}
@@ -0,0 +1,107 @@
+package interpreter
+
+import "golox/lox/token"
+
+// Classes overload operators by defining special methods. For a binary operator
+// the method is looked up on the left operand and called with the right one, so
+// 'a + b' calls 'a.__add(b)'. If the left operand doesn't define it, the right
+// operand's reflected method is called with the left one: '2 * v' calls
+// 'v.__rmul(2)'. Comparisons fall back to the mirrored method of the right
+// operand instead: 'a > b' calls 'a.__gt(b)' if it exists and 'b.__lt(a)'
+// otherwise. '==' tries '__eq' on either operand unless the other one is null,
+// also for the elements of lists and maps, and '!=' negates it. Unary minus
+// calls '__neg()', and indexing calls '__index(i)' and '__setindex(i, v)'.
+var binaryMethods = map[token.TokenType]string{
+	token.Plus:         "__add",
+	token.Minus:        "__sub",
+	token.Star:         "__mul",
+	token.Slash:        "__div",
+	token.Percent:      "__mod",
+	token.StarStar:     "__pow",
+	token.DotDot:       "__concat",
+	token.Less:         "__lt",
+	token.LessEqual:    "__le",
+	token.Greater:      "__gt",
+	token.GreaterEqual: "__ge",
+}
+
+var reflectedMethods = map[token.TokenType]string{
+	token.Plus:     "__radd",
+	token.Minus:    "__rsub",
+	token.Star:     "__rmul",
+	token.Slash:    "__rdiv",
+	token.Percent:  "__rmod",
+	token.StarStar: "__rpow",
+	token.DotDot:   "__rconcat",
+}
+
+var mirroredMethods = map[token.TokenType]string{
+	token.Less:         "__gt",
+	token.LessEqual:    "__ge",
+	token.Greater:      "__lt",
+	token.GreaterEqual: "__le",
+}
+
+// overloadBinary applies a binary operator through a special method, if one of
+// the operands defines it.
+func (interp *Interpreter) overloadBinary(operator *token.Token, left, right interface{}) (interface{}, bool, error) {
+	switch operator.Type {
+	case token.EqualEqual, token.BangEqual:
+		eq, overloaded, err := interp.overloadEqual(operator, left, right)
+		if !overloaded || err != nil {
+			return nil, overloaded, err
+		}
+		return eq == (operator.Type == token.EqualEqual), true, nil
+	case token.Less, token.LessEqual, token.Greater, token.GreaterEqual:
+		result, overloaded, err := interp.callSpecial(operator, left, binaryMethods[operator.Type], right)
+		if !overloaded && err == nil {
+			result, overloaded, err = interp.callSpecial(operator, right, mirroredMethods[operator.Type], left)
+		}
+		if !overloaded || err != nil {
+			return nil, overloaded, err
+		}
+		return isTruthy(result), true, nil
+	}
+
+	name, found := binaryMethods[operator.Type]
+	if !found {
+		return nil, false, nil
+	}
+	result, overloaded, err := interp.callSpecial(operator, left, name, right)
+	if !overloaded && err == nil {
+		result, overloaded, err = interp.callSpecial(operator, right, reflectedMethods[operator.Type], left)
+	}
+	return result, overloaded, err
+}
+
+// overloadEqual compares two values through '__eq' on either of them. Nothing is
+// equal to null but null, so comparing with null never calls '__eq'.
+func (interp *Interpreter) overloadEqual(tok *token.Token, left, right interface{}) (bool, bool, error) {
+	if left == nil || right == nil {
+		return false, false, nil
+	}
+	result, overloaded, err := interp.callSpecial(tok, left, "__eq", right)
+	if !overloaded && err == nil {
+		result, overloaded, err = interp.callSpecial(tok, right, "__eq", left)
+	}
+	return isTruthy(result), overloaded, err
+}
+
+// callSpecial calls the special method with the given name on receiver. It
+// reports false if the receiver isn't an instance or its class doesn't define
+// the method.
+func (interp *Interpreter) callSpecial(tok *token.Token, receiver interface{}, name string, args ...interface{}) (interface{}, bool, error) {
+	instance, ok := receiver.(*LoxInstance)
+	if !ok {
+		return nil, false, nil
+	}
+	method, found := instance.class.FindMethod(name)
+	if !found {
+		return nil, false, nil
+	}
+	result, err := interp.callFunction(method.Bind(instance), args, tok)
+	if retval, ok := result.(*ReturnValue); ok {
+		result = retval.First()
+	}
+	return result, true, err
+}
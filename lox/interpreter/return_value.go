@@ -0,0 +1,26 @@
+package interpreter
+
+// ReturnValue holds the values of an executed return statement. It is returned
+// as an error so that it unwinds every enclosing block and loop up to the
+// function call, no matter which values are being returned.
+type ReturnValue struct {
+	Values []interface{}
+}
+
+func NewReturnValue(values []interface{}) *ReturnValue {
+	return &ReturnValue{
+		Values: values,
+	}
+}
+
+func (rv *ReturnValue) Error() string {
+	return "'return' outside of a function"
+}
+
+// First returns the value used when a call appears in a single value context.
+func (rv *ReturnValue) First() interface{} {
+	if len(rv.Values) == 0 {
+		return nil
+	}
+	return rv.Values[0]
+}
@@ -11,10 +11,12 @@ import (
 	reporter "golox/lox/reporter"
 	"golox/lox/resolver"
 	"golox/lox/scanner"
+	"golox/lox/statement"
 )
 
 type Lox struct {
 	args            []string
+	file            string
 	hadError        bool
 	hadRuntimeError bool
 	scanner         *scanner.Scanner
@@ -24,7 +26,7 @@ type Lox struct {
 
 func NewLox(args []string) *Lox {
 	reporter := reporter.NewErrorReporter()
-	return &Lox{
+	lox := &Lox{
 		args:            args,
 		hadError:        false,
 		hadRuntimeError: false,
@@ -32,6 +34,8 @@ func NewLox(args []string) *Lox {
 		interp:          interpreter.NewInterpreter(reporter),
 		reporter:        reporter,
 	}
+	lox.interp.SetModuleLoader(lox.loadModule)
+	return lox
 }
 
 func (lox *Lox) Exec() {
@@ -71,12 +75,9 @@ func (lox *Lox) RunPrompt() error {
 }
 
 func (lox *Lox) RunScript(script string) error {
-	source, err := os.ReadFile(script)
-	if err != nil {
-		err = fmt.Errorf("run script: %w", err)
+	if err := lox.RunFile(script); err != nil {
 		return err
 	}
-	lox.Run(string(source), false)
 	if lox.hadError {
 		os.Exit(65)
 	}
@@ -86,12 +87,25 @@ func (lox *Lox) RunScript(script string) error {
 	return nil
 }
 
+// RunFile runs a script file. Modules it imports are looked up relative to it.
+func (lox *Lox) RunFile(script string) error {
+	source, err := os.ReadFile(script)
+	if err != nil {
+		err = fmt.Errorf("run script: %w", err)
+		return err
+	}
+	lox.file = script
+	lox.Run(string(source), false)
+	return nil
+}
+
 func (lox *Lox) Run(source string, repl bool) {
 	lox.scanner.Reset()
+	lox.scanner.SetFile(lox.file)
 	tokens := lox.scanner.ScanTokens(source)
 	parser := ast.NewParser(tokens, lox.reporter)
 	statements, err := parser.Parse()
-	if err != nil {
+	if err != nil || lox.scanner.HadError() {
 		lox.hadError = true
 		return
 	}
@@ -109,6 +123,28 @@ func (lox *Lox) Run(source string, repl bool) {
 	//fmt.Println(ast.NewPrinter().Print(tree))
 }
 
+// loadModule reads, parses and resolves an imported module for the interpreter.
+func (lox *Lox) loadModule(path string) ([]statement.Stmt, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scanner := scanner.NewScanner(lox.reporter)
+	scanner.SetFile(path)
+	tokens := scanner.ScanTokens(string(source))
+	statements, err := ast.NewParser(tokens, lox.reporter).Parse()
+	if err != nil {
+		return nil, err
+	}
+	if scanner.HadError() {
+		return nil, fmt.Errorf("syntax error in %s", path)
+	}
+	if err = resolver.New(lox.interp, lox.reporter).Resolve(statements); err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
 func (lox *Lox) HadError() bool {
 	return lox.hadError || lox.hadRuntimeError
 }
@@ -0,0 +1,7 @@
+// Package node declares the statement interface below both the expression and
+// the statement packages, so that a function expression can hold its body.
+package node
+
+type Stmt interface {
+	Stmt()
+}
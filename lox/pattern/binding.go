@@ -0,0 +1,16 @@
+package pattern
+
+import "golox/lox/token"
+
+// Binding matches any value and binds it to a name in the scope of the case.
+type Binding struct {
+	Name *token.Token
+}
+
+func NewBinding(name *token.Token) *Binding {
+	return &Binding{
+		Name: name,
+	}
+}
+
+func (p *Binding) Pattern() {}
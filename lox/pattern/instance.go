@@ -0,0 +1,27 @@
+package pattern
+
+import (
+	"golox/lox/expression"
+	"golox/lox/token"
+)
+
+// Instance matches instances of a class or its subclasses whose fields match
+// the field patterns, as in 'Point(x: 0, y: y)'. 'Point(x)' is short for
+// 'Point(x: x)'.
+type Instance struct {
+	Class    *expression.Variable
+	Paren    *token.Token // for reporting location
+	Fields   []*token.Token
+	Patterns []Pattern
+}
+
+func NewInstance(class *expression.Variable, paren *token.Token, fields []*token.Token, patterns []Pattern) *Instance {
+	return &Instance{
+		Class:    class,
+		Paren:    paren,
+		Fields:   fields,
+		Patterns: patterns,
+	}
+}
+
+func (p *Instance) Pattern() {}
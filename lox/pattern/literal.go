@@ -0,0 +1,18 @@
+package pattern
+
+import "golox/lox/token"
+
+// Literal matches values equal to a number, string, boolean or null.
+type Literal struct {
+	Token *token.Token // for reporting location
+	Value interface{}
+}
+
+func NewLiteral(tok *token.Token, value interface{}) *Literal {
+	return &Literal{
+		Token: tok,
+		Value: value,
+	}
+}
+
+func (p *Literal) Pattern() {}
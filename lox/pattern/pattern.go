@@ -0,0 +1,6 @@
+package pattern
+
+// Pattern is the left hand side of a case in a match statement.
+type Pattern interface {
+	Pattern()
+}
@@ -0,0 +1,16 @@
+package pattern
+
+import "golox/lox/token"
+
+// Wildcard is '_', which matches any value without binding it.
+type Wildcard struct {
+	Token *token.Token
+}
+
+func NewWildcard(tok *token.Token) *Wildcard {
+	return &Wildcard{
+		Token: tok,
+	}
+}
+
+func (p *Wildcard) Pattern() {}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"golox/lox/expression"
 	"golox/lox/interpreter"
+	"golox/lox/pattern"
 	"golox/lox/reporter"
 	"golox/lox/statement"
 	"golox/lox/token"
@@ -14,21 +15,37 @@ type FunctionType int
 const (
 	FunctionTypeNone FunctionType = iota
 	FunctionTypeFunc
+	FunctionTypeMethod
+	FunctionTypeInitializer
+	FunctionTypeGenerator // a function or method whose body yields
+)
+
+type ClassType int
+
+const (
+	ClassTypeNone ClassType = iota
+	ClassTypeClass
+	ClassTypeSubclass
+	ClassTypeStatic // static methods and class field initializers, which have no 'me'
 )
 
 type Resolver struct {
-	interp      *interpreter.Interpreter
-	reporter    *reporter.ErrorReporter
-	scopes      []map[string]bool
-	currentFunc FunctionType
+	interp       *interpreter.Interpreter
+	reporter     *reporter.ErrorReporter
+	scopes       []map[string]bool
+	consts       []map[string]*token.Token // the constants declared in each scope
+	currentFunc  FunctionType
+	currentClass ClassType
+	loopDepth    int
 }
 
 func New(interp *interpreter.Interpreter, reporter *reporter.ErrorReporter) *Resolver {
 	return &Resolver{
-		interp:      interp,
-		reporter:    reporter,
-		scopes:      make([]map[string]bool, 0),
-		currentFunc: FunctionTypeNone,
+		interp:       interp,
+		reporter:     reporter,
+		scopes:       make([]map[string]bool, 0),
+		currentFunc:  FunctionTypeNone,
+		currentClass: ClassTypeNone,
 	}
 }
 
@@ -57,8 +74,13 @@ func (r *Resolver) resolveStmts(statements []statement.Stmt) (interface{}, error
 
 func (r *Resolver) resolveStmt(stmt statement.Stmt) error {
 	switch v := stmt.(type) {
+	case *statement.BlockStmt:
+		_, err := r.resolveBlockStmt(v)
+		return err
 	case *statement.VarStmt:
 		return r.resolveVarStmt(v)
+	case *statement.ConstStmt:
+		return r.resolveConstStmt(v)
 	case *statement.FunctionStmt:
 		return r.resolveFunctionStmt(v)
 	case *statement.ExpressionStmt:
@@ -71,22 +93,68 @@ func (r *Resolver) resolveStmt(stmt statement.Stmt) error {
 		return r.resolveReturnStmt(v)
 	case *statement.WhileStmt:
 		return r.resolveWhileStmt(v)
+	case *statement.ForInStmt:
+		return r.resolveForInStmt(v)
+	case *statement.NumericForStmt:
+		return r.resolveNumericForStmt(v)
+	case *statement.RepeatStmt:
+		return r.resolveRepeatStmt(v)
+	case *statement.MatchStmt:
+		return r.resolveMatchStmt(v)
 	case *statement.ClassStmt:
 		return r.resolveClassStmt(v)
+	case *statement.ImportStmt:
+		if err := r.declare(v.Name); err != nil {
+			return err
+		}
+		return r.define(v.Name)
+	case *statement.TryStmt:
+		return r.resolveTryStmt(v)
+	case *statement.ThrowStmt:
+		return r.resolve(v.Value)
+	case *statement.YieldStmt:
+		return r.resolveYieldStmt(v)
+	case *statement.BreakStmt:
+		return r.resolveLoopJump(v.Keyword)
+	case *statement.ContinueStmt:
+		return r.resolveLoopJump(v.Keyword)
 	}
 	return nil
 }
 
 func (r *Resolver) resolveVarStmt(stmt *statement.VarStmt) error {
-	if err := r.declare(stmt.Name); err != nil {
-		return err
+	for _, name := range stmt.Names {
+		if err := r.declare(name); err != nil {
+			return err
+		}
 	}
-	if stmt.Initializer != nil {
-		if err := r.resolve(stmt.Initializer); err != nil {
+	for _, initializer := range stmt.Initializers {
+		if err := r.resolve(initializer); err != nil {
 			return err
 		}
 	}
-	return r.define(stmt.Name)
+	for _, name := range stmt.Names {
+		if err := r.define(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) resolveConstStmt(stmt *statement.ConstStmt) error {
+	if err := r.declare(stmt.Name); err != nil {
+		return err
+	}
+	if err := r.resolve(stmt.Initializer); err != nil {
+		return err
+	}
+	if err := r.define(stmt.Name); err != nil {
+		return err
+	}
+	if len(r.consts) > 0 {
+		r.consts[len(r.consts)-1][stmt.Name.Lexeme] = stmt.Name
+	}
+	return nil
 }
 
 func (r *Resolver) resolveFunctionStmt(stmt *statement.FunctionStmt) error {
@@ -100,11 +168,24 @@ func (r *Resolver) resolveFunctionStmt(stmt *statement.FunctionStmt) error {
 }
 
 func (r *Resolver) resolveFunction(function *statement.FunctionStmt, funcType FunctionType) error {
+	// initializers that yield are reported at the yield statement
+	if function.IsGenerator && funcType != FunctionTypeInitializer {
+		funcType = FunctionTypeGenerator
+	}
 	enclosingFunc := r.currentFunc
 	r.currentFunc = funcType
+	// loops don't reach into function bodies
+	enclosingLoopDepth := r.loopDepth
+	r.loopDepth = 0
 
 	r.beginScope()
-	for _, param := range function.Params {
+	for i, param := range function.Params {
+		// a default value can use the parameters before it
+		if function.Defaults[i] != nil {
+			if err := r.resolve(function.Defaults[i]); err != nil {
+				return err
+			}
+		}
 		if err := r.declare(param); err != nil {
 			return err
 		}
@@ -112,12 +193,21 @@ func (r *Resolver) resolveFunction(function *statement.FunctionStmt, funcType Fu
 			return err
 		}
 	}
+	if function.Rest != nil {
+		if err := r.declare(function.Rest); err != nil {
+			return err
+		}
+		if err := r.define(function.Rest); err != nil {
+			return err
+		}
+	}
 	if _, err := r.resolveStmts(function.Body); err != nil {
 		return err
 	}
 	r.endScope()
 
 	r.currentFunc = enclosingFunc
+	r.loopDepth = enclosingLoopDepth
 
 	return nil
 }
@@ -133,6 +223,11 @@ func (r *Resolver) resolveIfStmt(stmt *statement.IfStmt) error {
 	if err := r.resolve(stmt.ThenBranch); err != nil {
 		return err
 	}
+	for _, elif := range stmt.ElifBranches {
+		if err := r.resolveIfStmt(elif); err != nil {
+			return err
+		}
+	}
 	if stmt.ElseBranch != nil {
 		if err := r.resolve(stmt.ElseBranch); err != nil {
 			return err
@@ -149,27 +244,260 @@ func (r *Resolver) resolveReturnStmt(stmt *statement.ReturnStmt) error {
 	if r.currentFunc == FunctionTypeNone {
 		return r.reporter.Report("can't return from top-level code (outside of function)", stmt.Keyword)
 	}
-	if stmt.Value != nil {
-		return r.resolve(stmt.Value)
+	if len(stmt.Values) == 0 {
+		return nil
+	}
+	if r.currentFunc == FunctionTypeInitializer {
+		return r.reporter.Report("can't return a value from an initializer", stmt.Keyword)
+	}
+	if r.currentFunc == FunctionTypeGenerator {
+		return r.reporter.Report("can't return a value from a generator", stmt.Keyword)
+	}
+	for _, value := range stmt.Values {
+		if err := r.resolve(value); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+func (r *Resolver) resolveYieldStmt(stmt *statement.YieldStmt) error {
+	if r.currentFunc == FunctionTypeNone {
+		return r.reporter.Report("can't yield from top-level code (outside of function)", stmt.Keyword)
+	}
+	if r.currentFunc == FunctionTypeInitializer {
+		return r.reporter.Report("can't yield from an initializer", stmt.Keyword)
+	}
+	return r.resolve(stmt.Value)
+}
+
 func (r *Resolver) resolveWhileStmt(stmt *statement.WhileStmt) error {
 	if err := r.resolve(stmt.Condition); err != nil {
 		return err
 	}
+	r.loopDepth++
 	if err := r.resolve(stmt.Body); err != nil {
 		return err
 	}
+	r.loopDepth--
+	if stmt.Increment != nil {
+		if err := r.resolve(stmt.Increment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) resolveForInStmt(stmt *statement.ForInStmt) error {
+	if err := r.resolve(stmt.Iterable); err != nil {
+		return err
+	}
+
+	r.beginScope()
+	for _, name := range stmt.Names {
+		if err := r.declare(name); err != nil {
+			return err
+		}
+		if err := r.define(name); err != nil {
+			return err
+		}
+	}
+	r.loopDepth++
+	if err := r.resolve(stmt.Body); err != nil {
+		return err
+	}
+	r.loopDepth--
+	r.endScope()
+	return nil
+}
+
+func (r *Resolver) resolveNumericForStmt(stmt *statement.NumericForStmt) error {
+	bounds := []expression.Expression{stmt.Start, stmt.Stop}
+	if stmt.Step != nil {
+		bounds = append(bounds, stmt.Step)
+	}
+	for _, bound := range bounds {
+		if err := r.resolve(bound); err != nil {
+			return err
+		}
+	}
+
+	r.beginScope()
+	if err := r.declare(stmt.Name); err != nil {
+		return err
+	}
+	if err := r.define(stmt.Name); err != nil {
+		return err
+	}
+	r.loopDepth++
+	if err := r.resolve(stmt.Body); err != nil {
+		return err
+	}
+	r.loopDepth--
+	r.endScope()
+	return nil
+}
+
+func (r *Resolver) resolveRepeatStmt(stmt *statement.RepeatStmt) error {
+	r.beginScope()
+	r.loopDepth++
+	if _, err := r.resolveStmts(stmt.Body); err != nil {
+		return err
+	}
+	r.loopDepth--
+	if err := r.resolve(stmt.Condition); err != nil {
+		return err
+	}
+	r.endScope()
+	return nil
+}
+
+func (r *Resolver) resolveMatchStmt(stmt *statement.MatchStmt) error {
+	if err := r.resolve(stmt.Value); err != nil {
+		return err
+	}
+	for _, c := range stmt.Cases {
+		// names bound by the pattern are only visible in their own case
+		r.beginScope()
+		if err := r.resolvePattern(c.Pattern); err != nil {
+			return err
+		}
+		if c.Guard != nil {
+			if err := r.resolve(c.Guard); err != nil {
+				return err
+			}
+		}
+		if _, err := r.resolveStmts(c.Body); err != nil {
+			return err
+		}
+		r.endScope()
+	}
+	return nil
+}
+
+func (r *Resolver) resolvePattern(pat pattern.Pattern) error {
+	switch p := pat.(type) {
+	case *pattern.Binding:
+		if err := r.declare(p.Name); err != nil {
+			return err
+		}
+		return r.define(p.Name)
+	case *pattern.Instance:
+		if err := r.resolve(p.Class); err != nil {
+			return err
+		}
+		for _, field := range p.Patterns {
+			if err := r.resolvePattern(field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) resolveTryStmt(stmt *statement.TryStmt) error {
+	r.beginScope()
+	if _, err := r.resolveStmts(stmt.Body); err != nil {
+		return err
+	}
+	r.endScope()
+
+	if stmt.CatchBody != nil {
+		r.beginScope()
+		if stmt.CatchName != nil {
+			if err := r.declare(stmt.CatchName); err != nil {
+				return err
+			}
+			if err := r.define(stmt.CatchName); err != nil {
+				return err
+			}
+		}
+		if _, err := r.resolveStmts(stmt.CatchBody); err != nil {
+			return err
+		}
+		r.endScope()
+	}
+
+	if stmt.FinallyBody != nil {
+		r.beginScope()
+		if _, err := r.resolveStmts(stmt.FinallyBody); err != nil {
+			return err
+		}
+		r.endScope()
+	}
+	return nil
+}
+
+func (r *Resolver) resolveLoopJump(keyword *token.Token) error {
+	if r.loopDepth == 0 {
+		return r.reporter.Report(fmt.Sprintf("can't use '%s' outside of a loop", keyword.Lexeme), keyword)
+	}
 	return nil
 }
 
 func (r *Resolver) resolveClassStmt(stmt *statement.ClassStmt) error {
+	enclosingClass := r.currentClass
+	r.currentClass = ClassTypeClass
+
 	if err := r.declare(stmt.Name); err != nil {
 		return err
 	}
-	return r.define(stmt.Name)
+	if err := r.define(stmt.Name); err != nil {
+		return err
+	}
+
+	r.currentClass = ClassTypeStatic
+	for _, field := range stmt.Fields {
+		for _, initializer := range field.Initializers {
+			if err := r.resolve(initializer); err != nil {
+				return err
+			}
+		}
+	}
+	for _, method := range stmt.StaticMethods {
+		if err := r.resolveFunction(method, FunctionTypeMethod); err != nil {
+			return err
+		}
+	}
+	r.currentClass = ClassTypeClass
+
+	if stmt.Superclass != nil {
+		if stmt.Superclass.Name.Lexeme == stmt.Name.Lexeme {
+			return r.reporter.Report(fmt.Sprintf("class '%s' can't inherit from itself", stmt.Name.Lexeme), stmt.Superclass.Name)
+		}
+		r.currentClass = ClassTypeSubclass
+		if err := r.resolve(stmt.Superclass); err != nil {
+			return err
+		}
+		r.beginScope()
+		r.peekScope()["base"] = true
+	}
+
+	r.beginScope()
+	r.peekScope()["me"] = true
+	for _, method := range stmt.Methods {
+		funcType := FunctionTypeMethod
+		if method.Name.Lexeme == "init" {
+			funcType = FunctionTypeInitializer
+		}
+		if err := r.resolveFunction(method, funcType); err != nil {
+			return err
+		}
+	}
+	for _, getter := range stmt.Getters {
+		if err := r.resolveFunction(getter, FunctionTypeMethod); err != nil {
+			return err
+		}
+	}
+	r.endScope()
+
+	if stmt.Superclass != nil {
+		r.endScope()
+	}
+
+	r.currentClass = enclosingClass
+
+	return nil
 }
 
 func (r *Resolver) declare(name *token.Token) error {
@@ -216,10 +544,18 @@ func (r *Resolver) resolveExpr(expr expression.Expression) error {
 		return r.resolveVarExpr(v)
 	case *expression.Assign:
 		return r.resolveAssignExpr(v)
+	case *expression.MultiAssign:
+		return r.resolveMultiAssignExpr(v)
+	case *expression.CompoundAssign:
+		return r.resolveCompoundAssignExpr(v)
 	case *expression.Binary:
 		return r.resolveBinaryExpr(v)
 	case *expression.Call:
 		return r.resolveCallExpr(v)
+	case *expression.Spawn:
+		return r.resolveCallExpr(v.Call)
+	case *expression.Wait:
+		return r.resolve(v.Task)
 	case *expression.Grouping:
 		return r.resolveGroupingExpr(v)
 	case *expression.Literal:
@@ -232,13 +568,31 @@ func (r *Resolver) resolveExpr(expr expression.Expression) error {
 		return r.resolveGetExpr(v)
 	case *expression.Set:
 		return r.resolveSetExpr(v)
+	case *expression.Me:
+		return r.resolveMeExpr(v)
+	case *expression.Base:
+		return r.resolveBaseExpr(v)
+	case *expression.Function:
+		return r.resolveFunctionExpr(v)
+	case *expression.List:
+		return r.resolveListExpr(v)
+	case *expression.Map:
+		return r.resolveMapExpr(v)
+	case *expression.Interpolation:
+		return r.resolveInterpolationExpr(v)
+	case *expression.Index:
+		return r.resolveIndexExpr(v)
+	case *expression.IndexSet:
+		return r.resolveIndexSetExpr(v)
 	}
 	return nil
 }
 
 func (r *Resolver) resolveVarExpr(expr *expression.Variable) error {
-	if len(r.scopes) > 0 && !r.peekScope()[expr.Name.Lexeme] {
-		return r.reporter.Report(fmt.Sprintf("can't read local variable '%s' in its own initializer", expr.Name.Lexeme), expr.Name)
+	if len(r.scopes) > 0 {
+		if defined, declared := r.peekScope()[expr.Name.Lexeme]; declared && !defined {
+			return r.reporter.Report(fmt.Sprintf("can't read local variable '%s' in its own initializer", expr.Name.Lexeme), expr.Name)
+		}
 	}
 	return r.resolveLocal(expr, expr.Name)
 }
@@ -247,9 +601,53 @@ func (r *Resolver) resolveAssignExpr(expr *expression.Assign) error {
 	if err := r.resolve(expr.Value); err != nil {
 		return err
 	}
+	if err := r.checkAssignable(expr.Name); err != nil {
+		return err
+	}
 	return r.resolveLocal(expr, expr.Name)
 }
 
+func (r *Resolver) resolveCompoundAssignExpr(expr *expression.CompoundAssign) error {
+	if err := r.resolve(expr.Value); err != nil {
+		return err
+	}
+	if variable, ok := expr.Target.(*expression.Variable); ok {
+		if err := r.checkAssignable(variable.Name); err != nil {
+			return err
+		}
+	}
+	// the target is read before it's written, resolving it as a read covers both
+	return r.resolve(expr.Target)
+}
+
+func (r *Resolver) resolveMultiAssignExpr(expr *expression.MultiAssign) error {
+	for _, value := range expr.Values {
+		if err := r.resolve(value); err != nil {
+			return err
+		}
+	}
+	for _, target := range expr.Targets {
+		switch t := target.(type) {
+		case *expression.Variable:
+			if err := r.checkAssignable(t.Name); err != nil {
+				return err
+			}
+			if err := r.resolveLocal(t, t.Name); err != nil {
+				return err
+			}
+		case *expression.Get:
+			if err := r.resolve(t.Object); err != nil {
+				return err
+			}
+		case *expression.Index:
+			if err := r.resolveIndexExpr(t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (r *Resolver) resolveLocal(expr expression.Expression, name *token.Token) error {
 	for i := len(r.scopes) - 1; i >= 0; i-- {
 		if _, ok := r.scopes[i][name.Lexeme]; ok {
@@ -260,6 +658,20 @@ func (r *Resolver) resolveLocal(expr expression.Expression, name *token.Token) e
 	return nil
 }
 
+// checkAssignable reports an assignment to a local constant. Global constants
+// are checked by the interpreter.
+func (r *Resolver) checkAssignable(name *token.Token) error {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if _, ok := r.scopes[i][name.Lexeme]; ok {
+			if decl, isConst := r.consts[i][name.Lexeme]; isConst {
+				return r.reporter.Report(interpreter.ConstantAssignmentMessage(name, decl), name)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
 func (r *Resolver) resolveBinaryExpr(expr *expression.Binary) error {
 	if err := r.resolve(expr.Left); err != nil {
 		return err
@@ -268,9 +680,13 @@ func (r *Resolver) resolveBinaryExpr(expr *expression.Binary) error {
 }
 
 func (r *Resolver) resolveCallExpr(expr *expression.Call) error {
-	r.resolve(expr.Callee)
+	if err := r.resolve(expr.Callee); err != nil {
+		return err
+	}
 	for _, arg := range expr.Args {
-		r.resolve(arg)
+		if err := r.resolve(arg); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -308,11 +724,87 @@ func (r *Resolver) resolveSetExpr(expr *expression.Set) error {
 	return nil
 }
 
+func (r *Resolver) resolveMeExpr(expr *expression.Me) error {
+	switch r.currentClass {
+	case ClassTypeNone:
+		return r.reporter.Report("can't use 'me' outside of a class", expr.Keyword)
+	case ClassTypeStatic:
+		return r.reporter.Report("can't use 'me' in a static method or class field", expr.Keyword)
+	}
+	return r.resolveLocal(expr, expr.Keyword)
+}
+
+func (r *Resolver) resolveBaseExpr(expr *expression.Base) error {
+	switch r.currentClass {
+	case ClassTypeNone:
+		return r.reporter.Report("can't use 'base' outside of a class", expr.Keyword)
+	case ClassTypeClass:
+		return r.reporter.Report("can't use 'base' in a class with no superclass", expr.Keyword)
+	case ClassTypeStatic:
+		return r.reporter.Report("can't use 'base' in a static method or class field", expr.Keyword)
+	}
+	return r.resolveLocal(expr, expr.Keyword)
+}
+
+func (r *Resolver) resolveFunctionExpr(expr *expression.Function) error {
+	function := statement.NewFunctionStmt(nil, expr.Params, expr.Defaults, expr.Rest, expr.Body, expr.IsGenerator)
+	return r.resolveFunction(function, FunctionTypeFunc)
+}
+
+func (r *Resolver) resolveListExpr(expr *expression.List) error {
+	for _, element := range expr.Elements {
+		if err := r.resolve(element); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) resolveMapExpr(expr *expression.Map) error {
+	for i := range expr.Keys {
+		if err := r.resolve(expr.Keys[i]); err != nil {
+			return err
+		}
+		if err := r.resolve(expr.Values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) resolveInterpolationExpr(expr *expression.Interpolation) error {
+	for _, part := range expr.Parts {
+		if err := r.resolve(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) resolveIndexExpr(expr *expression.Index) error {
+	if err := r.resolve(expr.Object); err != nil {
+		return err
+	}
+	return r.resolve(expr.Index)
+}
+
+func (r *Resolver) resolveIndexSetExpr(expr *expression.IndexSet) error {
+	if err := r.resolve(expr.Value); err != nil {
+		return err
+	}
+	if err := r.resolve(expr.Object); err != nil {
+		return err
+	}
+	return r.resolve(expr.Index)
+}
+
 func (r *Resolver) beginScope() {
 	scope := make(map[string]bool)
 	r.scopes = append(r.scopes, scope)
+	r.consts = append(r.consts, make(map[string]*token.Token))
 }
 
 func (r *Resolver) endScope() {
 	r.scopes = r.scopes[:len(r.scopes)-1]
+	r.consts = r.consts[:len(r.consts)-1]
 }
@@ -3,6 +3,7 @@ package scanner
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"unicode"
 
 	"golox/lox/reporter"
@@ -10,37 +11,57 @@ import (
 )
 
 var keywords = map[string]token.TokenType{
-	"and":    token.And,
-	"or":     token.Or,
-	"class":  token.Class,
-	"if":     token.If,
-	"then":   token.Then,
-	"end":    token.End,
-	"else":   token.Else,
-	"elif":   token.Elif,
-	"not":    token.Not,
-	"while":  token.While,
-	"for":    token.For,
-	"do":     token.Do,
-	"func":   token.Func,
-	"null":   token.Null,
-	"print":  token.Print,
-	"return": token.Return,
-	"base":   token.Base,
-	"me":     token.Me,
-	"true":   token.True,
-	"false":  token.False,
-	"var":    token.Var,
+	"and":      token.And,
+	"or":       token.Or,
+	"class":    token.Class,
+	"if":       token.If,
+	"then":     token.Then,
+	"end":      token.End,
+	"else":     token.Else,
+	"elif":     token.Elif,
+	"match":    token.Match,
+	"case":     token.Case,
+	"not":      token.Not,
+	"div":      token.Div,
+	"while":    token.While,
+	"for":      token.For,
+	"in":       token.In,
+	"do":       token.Do,
+	"repeat":   token.Repeat,
+	"until":    token.Until,
+	"func":     token.Func,
+	"null":     token.Null,
+	"print":    token.Print,
+	"return":   token.Return,
+	"yield":    token.Yield,
+	"spawn":    token.Spawn,
+	"wait":     token.Wait,
+	"break":    token.Break,
+	"continue": token.Continue,
+	"try":      token.Try,
+	"catch":    token.Catch,
+	"finally":  token.Finally,
+	"throw":    token.Throw,
+	"import":   token.Import,
+	"as":       token.As,
+	"base":     token.Base,
+	"me":       token.Me,
+	"true":     token.True,
+	"false":    token.False,
+	"var":      token.Var,
+	"const":    token.Const,
 }
 
 type Scanner struct {
 	source              string
+	file                string
 	start               int
 	current             int
 	line                int
 	lastDoubleQuoteLine int
 	tokens              []*token.Token
 	reporter            *reporter.ErrorReporter
+	hadError            bool
 }
 
 func NewScanner(reporter *reporter.ErrorReporter) *Scanner {
@@ -56,18 +77,35 @@ func (s *Scanner) ScanTokens(source string) []*token.Token {
 		s.start = s.current
 		s.scanToken()
 	}
-	s.tokens = append(s.tokens, token.NewToken(token.EOF, "", nil, s.line, source))
+	s.tokens = append(s.tokens, s.newToken(token.EOF, "", nil, s.line))
 	return s.tokens
 }
 
+// SetFile sets the file name that the scanned tokens report errors with.
+func (s *Scanner) SetFile(file string) {
+	s.file = file
+}
+
+// HadError reports whether the scanner reported an error since it was last reset.
+func (s *Scanner) HadError() bool {
+	return s.hadError
+}
+
 func (s *Scanner) Reset() {
 	s.line = 1
 	s.source = ""
+	s.file = ""
 	s.start = 0
 	s.current = 0
 	s.lastDoubleQuoteLine = 0
 	s.tokens = s.tokens[:0]
 	s.reporter = nil
+	s.hadError = false
+}
+
+func (s *Scanner) report(msg string, location *token.Token) {
+	s.hadError = true
+	s.reporter.Report(msg, location)
 }
 
 func (s *Scanner) isAtEnd() bool {
@@ -85,34 +123,76 @@ func (s *Scanner) scanToken() {
 		s.addToken(token.LeftBrace)
 	case '}':
 		s.addToken(token.RightBrace)
+	case '[':
+		s.addToken(token.LeftBracket)
+	case ']':
+		s.addToken(token.RightBracket)
 	case ',':
 		s.addToken(token.Comma)
+	case ':':
+		s.addToken(token.Colon)
 	case '.':
-		s.addMatchingToken('.', token.DotDot, token.Dot)
+		if s.peek() == '.' {
+			s.advance()
+			if s.peek() == '.' {
+				s.advance()
+				s.addToken(token.Ellipsis)
+			} else {
+				s.addMatchingToken('=', token.DotDotEqual, token.DotDot)
+			}
+		} else {
+			s.addToken(token.Dot)
+		}
 	case '-':
-		s.addToken(token.Minus)
+		s.addMatchingToken('=', token.MinusEqual, token.Minus)
 	case '+':
-		s.addToken(token.Plus)
+		s.addMatchingToken('=', token.PlusEqual, token.Plus)
 	case ';':
 		s.addToken(token.Semicolon)
 	case '*':
-		s.addToken(token.Star)
+		if s.peek() == '*' {
+			s.advance()
+			s.addToken(token.StarStar)
+		} else {
+			s.addMatchingToken('=', token.StarEqual, token.Star)
+		}
+	case '%':
+		s.addMatchingToken('=', token.PercentEqual, token.Percent)
+	case '&':
+		s.addToken(token.Ampersand)
+	case '|':
+		s.addToken(token.Pipe)
+	case '^':
+		s.addToken(token.Caret)
 	case '!':
 		s.addMatchingToken('=', token.BangEqual, token.Bang)
 	case '=':
 		s.addMatchingToken('=', token.EqualEqual, token.Equal)
 	case '<':
-		s.addMatchingToken('=', token.LessEqual, token.Less)
+		if s.peek() == '<' {
+			s.advance()
+			s.addToken(token.LessLess)
+		} else {
+			s.addMatchingToken('=', token.LessEqual, token.Less)
+		}
 	case '>':
-		s.addMatchingToken('=', token.GreaterEqual, token.Greater)
+		if s.peek() == '>' {
+			s.advance()
+			s.addToken(token.GreaterGreater)
+		} else {
+			s.addMatchingToken('=', token.GreaterEqual, token.Greater)
+		}
 	case '/':
 		if s.peek() == '/' { // comment
 			s.skipComment()
 		} else {
-			s.addToken(token.Slash)
+			s.addMatchingToken('=', token.SlashEqual, token.Slash)
 		}
 	case ' ', '\r', '\t':
 	case '\n':
+		if s.endsStatement() {
+			s.addToken(token.Newline)
+		}
 		s.line += 1
 	case '"':
 		s.addStringToken()
@@ -123,8 +203,8 @@ func (s *Scanner) scanToken() {
 		} else if s.isValidIdentifierStart(char) {
 			s.addIdentifierToken()
 		} else {
-			location := token.NewToken(token.EOF, "", nil, s.line, s.source)
-			s.reporter.Report(fmt.Sprintf("unexpected character: %c", rune(char)), location)
+			location := s.newToken(token.EOF, "", nil, s.line)
+			s.report(fmt.Sprintf("unexpected character: %c", rune(char)), location)
 		}
 	}
 }
@@ -144,21 +224,27 @@ func (s *Scanner) peek() byte {
 }
 
 func (s *Scanner) peekNext() byte {
-	if s.isAtEnd() {
+	if s.current+1 >= len(s.source) {
 		return 0
 	}
 	nextChar := s.source[s.current+1]
 	return nextChar
 }
 
+func (s *Scanner) newToken(kind token.TokenType, lexeme string, literal interface{}, line int) *token.Token {
+	tok := token.NewToken(kind, lexeme, literal, line, s.source)
+	tok.File = s.file
+	return tok
+}
+
 func (s *Scanner) addToken(toktype token.TokenType) {
 	text := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, token.NewToken(toktype, text, nil, s.line, s.source))
+	s.tokens = append(s.tokens, s.newToken(toktype, text, nil, s.line))
 }
 
 func (s *Scanner) addTokenWithValue(toktype token.TokenType, literal interface{}) {
 	text := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, token.NewToken(toktype, text, literal, s.line, s.source))
+	s.tokens = append(s.tokens, s.newToken(toktype, text, literal, s.line))
 }
 
 func (s *Scanner) addMatchingToken(char byte, doubleToken token.TokenType, singleToken token.TokenType) {
@@ -171,47 +257,194 @@ func (s *Scanner) addMatchingToken(char byte, doubleToken token.TokenType, singl
 }
 
 func (s *Scanner) skipComment() {
-	for s.peek() == '/' && !s.isAtEnd() {
+	for s.peek() != '\n' && !s.isAtEnd() {
 		s.advance()
 	}
 }
 
+// endsStatement reports whether a new line after the last scanned token terminates
+// a statement. Lines ending with an operator, a comma or an opening keyword like
+// 'then' and 'do' continue on the next line. A new line after 'catch' tells the
+// parser that the caught value isn't bound to a name.
+func (s *Scanner) endsStatement() bool {
+	if len(s.tokens) == 0 {
+		return false
+	}
+	switch s.tokens[len(s.tokens)-1].Type {
+	case token.Identifier,
+		token.String,
+		token.Interpolation,
+		token.Number,
+		token.True,
+		token.False,
+		token.Null,
+		token.Me,
+		token.RightParen,
+		token.RightBrace,
+		token.RightBracket,
+		token.End,
+		token.Return,
+		token.Break,
+		token.Continue,
+		token.Catch:
+
+		return true
+	}
+	return false
+}
+
+// addStringToken adds a String token, or an Interpolation token when the string
+// embeds expressions with "${...}". The literal of an Interpolation token holds the
+// string pieces and the scanned tokens of each expression in order.
 func (s *Scanner) addStringToken() {
-	s.consumeString()
+	s.lastDoubleQuoteLine = s.line
+
+	var (
+		parts []interface{}
+		sb    strings.Builder
+		// a broken "${...}" was reported, the rest of the string is only skipped
+		// so that it isn't scanned as code
+		failed bool
+	)
+	for s.peek() != '"' && !s.isAtEnd() {
+		if !failed && s.peek() == '$' && s.peekNext() == '{' {
+			s.advance()
+			s.advance()
+			if sb.Len() > 0 {
+				parts = append(parts, sb.String())
+				sb.Reset()
+			}
+			tokens, ok := s.scanInterpolation()
+			if !ok {
+				failed = true
+				continue
+			}
+			parts = append(parts, tokens)
+			continue
+		}
+		if s.peek() == '\n' {
+			s.line += 1
+		}
+		sb.WriteByte(s.advance())
+	}
+
 	if s.isAtEnd() {
-		location := token.NewToken(token.EOF, "", nil, s.line, s.source)
-		s.reporter.Report(fmt.Sprintf("unterminated string litteral, started at line %d", s.lastDoubleQuoteLine), location)
+		location := s.newToken(token.EOF, "", nil, s.line)
+		s.report(fmt.Sprintf("unterminated string litteral, started at line %d", s.lastDoubleQuoteLine), location)
 		return
 	}
+	s.lastDoubleQuoteLine = s.line
 	s.advance()
-	strlit := s.source[s.start+1 : s.current-1]
-	s.addTokenWithValue(token.String, strlit)
+
+	if failed {
+		// stand in for the string, the parser has nothing to report about it
+		s.addTokenWithValue(token.String, "")
+		return
+	}
+	if parts == nil {
+		s.addTokenWithValue(token.String, sb.String())
+		return
+	}
+	if sb.Len() > 0 {
+		parts = append(parts, sb.String())
+	}
+	s.addTokenWithValue(token.Interpolation, parts)
 }
 
-func (s *Scanner) consumeString() {
-	s.lastDoubleQuoteLine = s.line
-	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
+// scanInterpolation scans the expression of a "${...}" up to its closing brace.
+// The tokens keep the line they are on and the expression as their source, so
+// errors point inside the string. A "${" without a closing brace is reported
+// and the scanner is left right after it, for the string to end at its quote.
+func (s *Scanner) scanInterpolation() ([]*token.Token, bool) {
+	start := s.current
+	startLine := s.line
+	depth := 0
+	for !s.isAtEnd() {
+		c := s.peek()
+		if c == '}' && depth == 0 {
+			break
+		}
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '\n':
 			s.line += 1
+		case '"':
+			// skip over a string nested in the expression
+			s.advance()
+			for s.peek() != '"' && !s.isAtEnd() {
+				if s.peek() == '\n' {
+					s.line += 1
+				}
+				s.advance()
+			}
+			if s.isAtEnd() {
+				continue
+			}
 		}
 		s.advance()
 	}
-	if s.peek() == '"' {
-		s.lastDoubleQuoteLine = s.line
+
+	if s.isAtEnd() {
+		location := s.newToken(token.EOF, "", nil, startLine)
+		location.Source = s.source[start-len("${") : s.lineEnd(start)]
+		s.report("unterminated '${' in string interpolation", location)
+		s.current = start
+		s.line = startLine
+		return nil, false
+	}
+	source := s.source[start:s.current]
+	s.advance()
+
+	if strings.TrimSpace(source) == "" {
+		location := s.newToken(token.EOF, "", nil, startLine)
+		location.Source = s.source[start-len("${") : s.current]
+		s.report("empty expression in string interpolation", location)
+		return nil, false
+	}
+
+	scanner := NewScanner(s.reporter)
+	scanner.line = startLine
+	scanner.file = s.file
+	tokens := scanner.ScanTokens(source)
+	if scanner.HadError() {
+		s.hadError = true
 	}
+	return tokens, true
 }
 
+// lineEnd returns the offset of the end of the line the offset is on.
+func (s *Scanner) lineEnd(offset int) int {
+	if end := strings.IndexByte(s.source[offset:], '\n'); end >= 0 {
+		return offset + end
+	}
+	return len(s.source)
+}
+
+// addNumberToken adds an integer literal (int64) for whole numbers and a float
+// literal (float64) for numbers with a fractional part.
 func (s *Scanner) addNumberToken() {
 	s.consumeNumber()
 	if s.peek() == '.' && unicode.IsDigit(rune(s.peekNext())) {
 		s.advance()
 		s.consumeNumber()
+		numStr := s.source[s.start:s.current]
+		num, err := strconv.ParseFloat(numStr, 64)
+		if err != nil {
+			location := s.newToken(token.EOF, "", nil, s.line)
+			s.report(fmt.Sprintf("internal error: can't parse float: %s", numStr), location)
+			return
+		}
+		s.addTokenWithValue(token.Number, num)
+		return
 	}
 	numStr := s.source[s.start:s.current]
-	num, err := strconv.ParseFloat(numStr, 64)
+	num, err := strconv.ParseInt(numStr, 10, 64)
 	if err != nil {
-		location := token.NewToken(token.EOF, "", nil, s.line, s.source)
-		s.reporter.Report(fmt.Sprintf("internal error: can't parse float: %s", numStr), location)
+		location := s.newToken(token.EOF, "", nil, s.line)
+		s.report(fmt.Sprintf("integer literal out of range: %s", numStr), location)
 		return
 	}
 	s.addTokenWithValue(token.Number, num)
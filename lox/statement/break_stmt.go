@@ -0,0 +1,15 @@
+package statement
+
+import "golox/lox/token"
+
+type BreakStmt struct {
+	Keyword *token.Token // for reporting location
+}
+
+func NewBreakStmt(keyword *token.Token) *BreakStmt {
+	return &BreakStmt{
+		Keyword: keyword,
+	}
+}
+
+func (bs *BreakStmt) Stmt() {}
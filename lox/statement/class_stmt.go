@@ -1,16 +1,27 @@
 package statement
 
-import "golox/lox/token"
+import (
+	"golox/lox/expression"
+	"golox/lox/token"
+)
 
 type ClassStmt struct {
-	Name    *token.Token
-	Methods []*FunctionStmt
+	Name          *token.Token
+	Superclass    *expression.Variable
+	Fields        []*VarStmt // class level variables, shared by all instances
+	Methods       []*FunctionStmt
+	StaticMethods []*FunctionStmt
+	Getters       []*FunctionStmt
 }
 
-func NewClassStmt(name *token.Token, methods []*FunctionStmt) *ClassStmt {
+func NewClassStmt(name *token.Token, superclass *expression.Variable, fields []*VarStmt, methods, staticMethods, getters []*FunctionStmt) *ClassStmt {
 	return &ClassStmt{
-		Name:    name,
-		Methods: methods,
+		Name:          name,
+		Superclass:    superclass,
+		Fields:        fields,
+		Methods:       methods,
+		StaticMethods: staticMethods,
+		Getters:       getters,
 	}
 }
 
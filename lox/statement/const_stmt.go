@@ -0,0 +1,21 @@
+package statement
+
+import (
+	"golox/lox/expression"
+	"golox/lox/token"
+)
+
+// ConstStmt declares a variable that can't be assigned after its declaration.
+type ConstStmt struct {
+	Name        *token.Token
+	Initializer expression.Expression
+}
+
+func NewConstStmt(name *token.Token, initializer expression.Expression) *ConstStmt {
+	return &ConstStmt{
+		Name:        name,
+		Initializer: initializer,
+	}
+}
+
+func (cs *ConstStmt) Stmt() {}
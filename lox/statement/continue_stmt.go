@@ -0,0 +1,15 @@
+package statement
+
+import "golox/lox/token"
+
+type ContinueStmt struct {
+	Keyword *token.Token // for reporting location
+}
+
+func NewContinueStmt(keyword *token.Token) *ContinueStmt {
+	return &ContinueStmt{
+		Keyword: keyword,
+	}
+}
+
+func (cs *ContinueStmt) Stmt() {}
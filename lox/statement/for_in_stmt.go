@@ -0,0 +1,25 @@
+package statement
+
+import (
+	"golox/lox/expression"
+	"golox/lox/token"
+)
+
+// ForInStmt loops over the values of an iterable, as in 'for k, v in m do ... end'.
+type ForInStmt struct {
+	Names    []*token.Token // one or two loop variables
+	In       *token.Token   // for reporting location
+	Iterable expression.Expression
+	Body     Stmt
+}
+
+func NewForInStmt(names []*token.Token, in *token.Token, iterable expression.Expression, body Stmt) *ForInStmt {
+	return &ForInStmt{
+		Names:    names,
+		In:       in,
+		Iterable: iterable,
+		Body:     body,
+	}
+}
+
+func (fs *ForInStmt) Stmt() {}
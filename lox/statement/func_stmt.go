@@ -1,18 +1,29 @@
 package statement
 
-import "golox/lox/token"
+import (
+	"golox/lox/expression"
+	"golox/lox/token"
+)
 
 type FunctionStmt struct {
-	Name   *token.Token
-	Params []*token.Token
-	Body   []Stmt
+	Name     *token.Token
+	Params   []*token.Token
+	Defaults []expression.Expression // one per parameter, nil for parameters without a default
+	Rest     *token.Token            // the '...rest' parameter collecting extra arguments, or nil
+	Body     []Stmt
+
+	IsGenerator bool // the body contains a yield statement
 }
 
-func NewFunctionStmt(name *token.Token, params []*token.Token, body []Stmt) *FunctionStmt {
+func NewFunctionStmt(name *token.Token, params []*token.Token, defaults []expression.Expression, rest *token.Token, body []Stmt, isGenerator bool) *FunctionStmt {
 	return &FunctionStmt{
-		Name:   name,
-		Params: params,
-		Body:   body,
+		Name:     name,
+		Params:   params,
+		Defaults: defaults,
+		Rest:     rest,
+		Body:     body,
+
+		IsGenerator: isGenerator,
 	}
 }
 
@@ -5,11 +5,11 @@ import "golox/lox/expression"
 type IfStmt struct {
 	Condition    expression.Expression
 	ThenBranch   Stmt
-	ElifBranches []Stmt
+	ElifBranches []*IfStmt
 	ElseBranch   Stmt
 }
 
-func NewIfStmt(condition expression.Expression, thenBranch Stmt, elifBranches []Stmt, elseBranch Stmt) *IfStmt {
+func NewIfStmt(condition expression.Expression, thenBranch Stmt, elifBranches []*IfStmt, elseBranch Stmt) *IfStmt {
 	return &IfStmt{
 		Condition:    condition,
 		ThenBranch:   thenBranch,
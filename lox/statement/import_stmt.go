@@ -0,0 +1,19 @@
+package statement
+
+import "golox/lox/token"
+
+type ImportStmt struct {
+	Keyword *token.Token // for reporting location and resolving relative paths
+	Path    string
+	Name    *token.Token // alias, or the file name without its extension
+}
+
+func NewImportStmt(keyword *token.Token, path string, name *token.Token) *ImportStmt {
+	return &ImportStmt{
+		Keyword: keyword,
+		Path:    path,
+		Name:    name,
+	}
+}
+
+func (is *ImportStmt) Stmt() {}
@@ -0,0 +1,39 @@
+package statement
+
+import (
+	"golox/lox/expression"
+	"golox/lox/pattern"
+	"golox/lox/token"
+)
+
+// MatchStmt runs the body of the first case whose pattern matches the value and
+// whose guard, if any, holds. It's a runtime error when no case matches.
+type MatchStmt struct {
+	Keyword *token.Token // for reporting location
+	Value   expression.Expression
+	Cases   []*MatchCase
+}
+
+type MatchCase struct {
+	Pattern pattern.Pattern
+	Guard   expression.Expression // nil without an 'if' guard
+	Body    []Stmt
+}
+
+func NewMatchStmt(keyword *token.Token, value expression.Expression, cases []*MatchCase) *MatchStmt {
+	return &MatchStmt{
+		Keyword: keyword,
+		Value:   value,
+		Cases:   cases,
+	}
+}
+
+func NewMatchCase(pat pattern.Pattern, guard expression.Expression, body []Stmt) *MatchCase {
+	return &MatchCase{
+		Pattern: pat,
+		Guard:   guard,
+		Body:    body,
+	}
+}
+
+func (ms *MatchStmt) Stmt() {}
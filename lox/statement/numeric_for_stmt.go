@@ -0,0 +1,28 @@
+package statement
+
+import (
+	"golox/lox/expression"
+	"golox/lox/token"
+)
+
+// NumericForStmt is the Lua style 'for i = start, stop, step do ... end' loop. The
+// bounds are evaluated once and stop is inclusive.
+type NumericForStmt struct {
+	Name  *token.Token
+	Start expression.Expression
+	Stop  expression.Expression
+	Step  expression.Expression // nil when the step defaults to 1
+	Body  Stmt
+}
+
+func NewNumericForStmt(name *token.Token, start, stop, step expression.Expression, body Stmt) *NumericForStmt {
+	return &NumericForStmt{
+		Name:  name,
+		Start: start,
+		Stop:  stop,
+		Step:  step,
+		Body:  body,
+	}
+}
+
+func (fs *NumericForStmt) Stmt() {}
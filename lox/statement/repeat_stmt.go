@@ -0,0 +1,19 @@
+package statement
+
+import "golox/lox/expression"
+
+// RepeatStmt runs its body until the condition holds. The condition is part of
+// the body's scope, so it can see the body's local variables.
+type RepeatStmt struct {
+	Body      []Stmt
+	Condition expression.Expression
+}
+
+func NewRepeatStmt(body []Stmt, condition expression.Expression) *RepeatStmt {
+	return &RepeatStmt{
+		Body:      body,
+		Condition: condition,
+	}
+}
+
+func (rs *RepeatStmt) Stmt() {}
@@ -7,13 +7,13 @@ import (
 
 type ReturnStmt struct {
 	Keyword *token.Token // for reporting location
-	Value   expression.Expression
+	Values  []expression.Expression
 }
 
-func NewReturnStmt(keyword *token.Token, value expression.Expression) *ReturnStmt {
+func NewReturnStmt(keyword *token.Token, values []expression.Expression) *ReturnStmt {
 	return &ReturnStmt{
 		Keyword: keyword,
-		Value:   value,
+		Values:  values,
 	}
 }
 
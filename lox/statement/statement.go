@@ -1,5 +1,5 @@
 package statement
 
-type Stmt interface {
-	Stmt()
-}
+import "golox/lox/node"
+
+type Stmt = node.Stmt
@@ -0,0 +1,20 @@
+package statement
+
+import (
+	"golox/lox/expression"
+	"golox/lox/token"
+)
+
+type ThrowStmt struct {
+	Keyword *token.Token // for reporting location
+	Value   expression.Expression
+}
+
+func NewThrowStmt(keyword *token.Token, value expression.Expression) *ThrowStmt {
+	return &ThrowStmt{
+		Keyword: keyword,
+		Value:   value,
+	}
+}
+
+func (ts *ThrowStmt) Stmt() {}
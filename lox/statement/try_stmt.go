@@ -0,0 +1,21 @@
+package statement
+
+import "golox/lox/token"
+
+type TryStmt struct {
+	Body        []Stmt
+	CatchName   *token.Token // nil when the caught value isn't bound to a name
+	CatchBody   []Stmt       // nil without a catch clause
+	FinallyBody []Stmt       // nil without a finally clause
+}
+
+func NewTryStmt(body []Stmt, catchName *token.Token, catchBody []Stmt, finallyBody []Stmt) *TryStmt {
+	return &TryStmt{
+		Body:        body,
+		CatchName:   catchName,
+		CatchBody:   catchBody,
+		FinallyBody: finallyBody,
+	}
+}
+
+func (ts *TryStmt) Stmt() {}
@@ -6,14 +6,14 @@ import (
 )
 
 type VarStmt struct {
-	Name        *token.Token
-	Initializer expression.Expression
+	Names        []*token.Token
+	Initializers []expression.Expression
 }
 
-func NewVarStmt(name *token.Token, initalizer expression.Expression) *VarStmt {
+func NewVarStmt(names []*token.Token, initalizers []expression.Expression) *VarStmt {
 	return &VarStmt{
-		Name:        name,
-		Initializer: initalizer,
+		Names:        names,
+		Initializers: initalizers,
 	}
 }
 
@@ -5,12 +5,14 @@ import "golox/lox/expression"
 type WhileStmt struct {
 	Condition expression.Expression
 	Body      Stmt
+	Increment expression.Expression // set for desugared for loops, runs after 'continue' too
 }
 
-func NewWhileStmt(condition expression.Expression, body Stmt) *WhileStmt {
+func NewWhileStmt(condition expression.Expression, body Stmt, increment expression.Expression) *WhileStmt {
 	return &WhileStmt{
 		Condition: condition,
 		Body:      body,
+		Increment: increment,
 	}
 }
 
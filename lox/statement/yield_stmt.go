@@ -0,0 +1,20 @@
+package statement
+
+import (
+	"golox/lox/expression"
+	"golox/lox/token"
+)
+
+type YieldStmt struct {
+	Keyword *token.Token // for reporting location
+	Value   expression.Expression
+}
+
+func NewYieldStmt(keyword *token.Token, value expression.Expression) *YieldStmt {
+	return &YieldStmt{
+		Keyword: keyword,
+		Value:   value,
+	}
+}
+
+func (ys *YieldStmt) Stmt() {}
@@ -10,13 +10,21 @@ const (
 	RightParen
 	LeftBrace
 	RightBrace
+	LeftBracket
+	RightBracket
 	Comma
+	Colon
 	Dot
 	Semicolon
+	Newline
 	Minus
 	Plus
 	Slash
 	Star
+	Ampersand
+	Pipe
+	Caret
+	Percent
 
 	// one or two character tokens
 	Bang
@@ -28,10 +36,21 @@ const (
 	Equal
 	EqualEqual
 	DotDot // for string concatination
+	Ellipsis
+	LessLess
+	GreaterGreater
+	StarStar
+	PlusEqual
+	MinusEqual
+	StarEqual
+	SlashEqual
+	PercentEqual
+	DotDotEqual
 
 	// literals
 	Identifier
 	String
+	Interpolation // string with embedded expressions, literal holds the parts
 	Number
 
 	// keywords
@@ -43,19 +62,37 @@ const (
 	End
 	Else
 	Elif
+	Match
+	Case
 	Not
+	Div
 	While
 	For
+	In
 	Do
+	Repeat
+	Until
 	Func
 	Null
 	Print
 	Return
+	Yield
+	Spawn
+	Wait
+	Break
+	Continue
+	Try
+	Catch
+	Finally
+	Throw
+	Import
+	As
 	Base
 	Me
 	True
 	False
 	Var
+	Const
 
 	EOF
 )
@@ -65,13 +102,21 @@ var tokenTypeNames = []string{
 	"RightParen",
 	"LeftBrace",
 	"RightBrace",
+	"LeftBracket",
+	"RightBracket",
 	"Comma",
+	"Colon",
 	"Dot",
 	"Semicolon",
+	"Newline",
 	"Minus",
 	"Plus",
 	"Slash",
 	"Star",
+	"Ampersand",
+	"Pipe",
+	"Caret",
+	"Percent",
 	"Bang",
 	"BangEqual",
 	"Less",
@@ -81,8 +126,19 @@ var tokenTypeNames = []string{
 	"Equal",
 	"EqualEqual",
 	"DotDot",
+	"Ellipsis",
+	"LessLess",
+	"GreaterGreater",
+	"StarStar",
+	"PlusEqual",
+	"MinusEqual",
+	"StarEqual",
+	"SlashEqual",
+	"PercentEqual",
+	"DotDotEqual",
 	"Identifier",
 	"String",
+	"Interpolation",
 	"Number",
 	"And",
 	"Or",
@@ -92,19 +148,37 @@ var tokenTypeNames = []string{
 	"End",
 	"Else",
 	"Elif",
+	"Match",
+	"Case",
 	"Not",
+	"Div",
 	"While",
 	"For",
+	"In",
 	"Do",
+	"Repeat",
+	"Until",
 	"Func",
 	"Null",
 	"Print",
 	"Return",
+	"Yield",
+	"Spawn",
+	"Wait",
+	"Break",
+	"Continue",
+	"Try",
+	"Catch",
+	"Finally",
+	"Throw",
+	"Import",
+	"As",
 	"Base",
 	"Me",
 	"True",
 	"False",
 	"Var",
+	"Const",
 	"EOF",
 }
 
@@ -122,19 +196,37 @@ func (t TokenType) IsKeyword() bool {
 		End,
 		Else,
 		Elif,
+		Match,
+		Case,
 		Not,
+		Div,
 		While,
 		For,
+		In,
 		Do,
+		Repeat,
+		Until,
 		Func,
 		Null,
 		Print,
 		Return,
+		Yield,
+		Spawn,
+		Wait,
+		Break,
+		Continue,
+		Try,
+		Catch,
+		Finally,
+		Throw,
+		Import,
+		As,
 		Base,
 		Me,
 		True,
 		False,
-		Var:
+		Var,
+		Const:
 
 		return true
 	}
@@ -162,6 +254,6 @@ func NewToken(kind TokenType, lexeme string, literal interface{}, line int, sour
 		Literal: literal,
 		Line:    line,
 		Source:  source,
-		File:    "", // TODO
+		File:    "", // set by the scanner
 	}
 }
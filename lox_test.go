@@ -2,8 +2,9 @@ package main
 
 import (
 	"golox/lox"
-	"os"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func TestLox(t *testing.T) {
@@ -15,18 +16,88 @@ func TestLox(t *testing.T) {
 		{"./tests/if_else.lox", false},
 		{"./tests/func.lox", false},
 		{"./tests/closure.lox", false},
+		{"./tests/elif.lox", false},
+		{"./tests/method.lox", false},
+		{"./tests/me_error.lox", true},
+		{"./tests/init.lox", false},
+		{"./tests/init_return_error.lox", true},
+		{"./tests/inheritance.lox", false},
+		{"./tests/inherit_self_error.lox", true},
+		{"./tests/inherit_non_class_error.lox", true},
+		{"./tests/base_error.lox", true},
+		{"./tests/lambda.lox", false},
+		{"./tests/multiple_return.lox", false},
+		{"./tests/multiple_return_error.lox", true},
+		{"./tests/newline.lox", false},
+		{"./tests/break_continue.lox", false},
+		{"./tests/break_error.lox", true},
+		{"./tests/return.lox", false},
+		{"./tests/list.lox", false},
+		{"./tests/list_bounds_error.lox", true},
+		{"./tests/map.lox", false},
+		{"./tests/map_key_error.lox", true},
+		{"./tests/interpolation.lox", false},
+		{"./tests/interpolation_error.lox", true},
+		{"./tests/interpolation_empty_error.lox", true},
+		{"./tests/interpolation_unterminated_error.lox", true},
+		{"./tests/exception.lox", false},
+		{"./tests/exception_uncaught.lox", true},
+		{"./tests/import.lox", false},
+		{"./tests/import_cycle_error.lox", true},
+		{"./tests/integer.lox", false},
+		{"./tests/bitwise_error.lox", true},
+		{"./tests/compound_assign.lox", false},
+		{"./tests/modulo_error.lox", true},
+		{"./tests/for_in.lox", false},
+		{"./tests/for_in_error.lox", true},
+		{"./tests/for_in_iter_error.lox", true},
+		{"./tests/range_error.lox", true},
+		{"./tests/numeric_for.lox", false},
+		{"./tests/numeric_for_error.lox", true},
+		{"./tests/match.lox", false},
+		{"./tests/match_error.lox", true},
+		{"./tests/parameters.lox", false},
+		{"./tests/parameters_error.lox", true},
+		{"./tests/static.lox", false},
+		{"./tests/static_me_error.lox", true},
+		{"./tests/operators.lox", false},
+		{"./tests/operators_error.lox", true},
 		{"./tests/scoped_error.lox", true},
+		{"./tests/generator.lox", false},
+		{"./tests/generator_error.lox", true},
+		{"./tests/generator_running_error.lox", true},
+		{"./tests/spawn.lox", false},
+		{"./tests/spawn_error.lox", true},
+		{"./tests/const.lox", false},
+		{"./tests/const_error.lox", true},
+		{"./tests/const_global_error.lox", true},
 	}
 
 	for _, test := range tests {
-		f, err := os.ReadFile(test.script)
-		if err != nil {
+		vm := lox.NewLox(nil)
+		if err := vm.RunFile(test.script); err != nil {
 			t.Errorf("unable to read script: %s", test.script)
 		}
-		vm := lox.NewLox(nil)
-		vm.Run(string(f), false)
 		if vm.HadError() != test.expectError {
 			t.Errorf("%s: expected error: %v, got: %v", test.script, test.expectError, vm.HadError())
 		}
 	}
 }
+
+func TestAbandonedGenerators(t *testing.T) {
+	before := runtime.NumGoroutine()
+	vm := lox.NewLox(nil)
+	if err := vm.RunFile("./tests/generator_abandon.lox"); err != nil || vm.HadError() {
+		t.Fatalf("unable to run script: %v", err)
+	}
+
+	// the goroutines of abandoned generators stop once the generators are collected,
+	// or at the latest when the script has finished
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if leaked := runtime.NumGoroutine() - before; leaked > 0 {
+		t.Errorf("%d goroutines of abandoned generators are still running", leaked)
+	}
+}